@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/app"
+	"github.com/Meystergod/gochat/internal/config"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	migrateUsage = "usage: app migrate up | down [steps] | status"
+	configUsage  = "usage: app config print"
+)
+
+func runCommand(ctx context.Context, application *app.Application, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(ctx, application, args[1:])
+	default:
+		return errors.Errorf("unknown command %q", args[0])
+	}
+}
+
+func runMigrate(ctx context.Context, application *app.Application, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := application.MigrateUp(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("applied %d migration(s)\n", len(applied))
+	case "down":
+		steps := 1
+
+		if len(args) > 1 {
+			var err error
+
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errors.New(migrateUsage)
+			}
+		}
+
+		reverted, err := application.MigrateDown(ctx, steps)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("reverted %d migration(s)\n", len(reverted))
+	case "status":
+		statuses, err := application.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tNAME\tAPPLIED AT")
+
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+
+			fmt.Fprintf(writer, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+
+		return writer.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
+
+// runConfig prints the effective configuration. When the configuration is
+// invalid, its problems are listed after the settings.
+func runConfig(cfg *config.Config, args []string, invalid *config.ValidationError) error {
+	if len(args) != 1 || args[0] != "print" {
+		return errors.New(configUsage)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "KEY\tVALUE\tSOURCE\tENV")
+
+	for _, setting := range cfg.Settings() {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", setting.Key, setting.Value, setting.Source, setting.Env)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	if invalid == nil {
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Println("PROBLEMS")
+
+	for _, problem := range invalid.Problems {
+		fmt.Println(problem)
+	}
+
+	return invalid
+}
@@ -2,18 +2,42 @@ package main
 
 import (
 	"context"
+	"flag"
+	"os"
 
 	"github.com/Meystergod/gochat/internal/app"
 	"github.com/Meystergod/gochat/internal/config"
 	"github.com/Meystergod/gochat/pkg/logging"
+
+	"github.com/pkg/errors"
 )
 
 func main() {
 	logger := logging.NewDefaultLogger()
 
-	cfg := config.GetConfig()
-	if cfg == nil {
-		logger.Fatal().Msg("reading config error")
+	var invalid *config.ValidationError
+
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil && !errors.As(err, &invalid) {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+
+		logger.Fatal().Msgf("reading config error: %s", err)
+	}
+
+	// config commands only inspect the configuration, so they must work
+	// without a reachable database and print an invalid configuration too
+	if len(args) > 0 && args[0] == "config" {
+		if err = runConfig(cfg, args[1:], invalid); err != nil {
+			logger.Fatal().Msgf("running command error: %s", err)
+		}
+
+		return
+	}
+
+	if invalid != nil {
+		logger.Fatal().Msgf("reading config error: %s", invalid)
 	}
 
 	loggerDeps := &logging.LoggerDeps{
@@ -22,7 +46,7 @@ func main() {
 		LogSize:  cfg.Log.LogSize,
 		LogAge:   cfg.Log.LogAge,
 	}
-	logger, err := logging.NewLogger(loggerDeps)
+	logger, err = logging.NewLogger(loggerDeps)
 	if err != nil {
 		logger.Fatal().Msgf("creating logger error: %s", err)
 	}
@@ -41,6 +65,14 @@ func main() {
 
 	logger.Debug().Msg("created new application")
 
+	if len(args) > 0 {
+		if err := runCommand(ctx, application, args); err != nil {
+			logger.Fatal().Msgf("running command error: %s", err)
+		}
+
+		return
+	}
+
 	if err := application.Run(ctx); err != nil {
 		logger.Fatal().Msgf("running application error: %s", err)
 	}
@@ -3,56 +3,212 @@ package app
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/broker"
 	"github.com/Meystergod/gochat/internal/config"
 	"github.com/Meystergod/gochat/internal/controller"
 	"github.com/Meystergod/gochat/internal/delivery/http/v1/httpecho"
+	"github.com/Meystergod/gochat/internal/realtime"
+	"github.com/Meystergod/gochat/internal/repository/repository_message/mongodb"
+	"github.com/Meystergod/gochat/internal/repository/repository_room/mongodb"
+	"github.com/Meystergod/gochat/internal/repository/repository_session/mongodb"
+	"github.com/Meystergod/gochat/internal/repository/repository_token/mongodb"
 	"github.com/Meystergod/gochat/internal/repository/repository_user/mongodb"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_auth"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_message"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_password"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_room"
 	"github.com/Meystergod/gochat/internal/usecase/usecase_user"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_verification"
 	"github.com/Meystergod/gochat/internal/utils"
 	"github.com/Meystergod/gochat/pkg/client"
+	"github.com/Meystergod/gochat/pkg/hasher"
+	"github.com/Meystergod/gochat/pkg/health"
 	"github.com/Meystergod/gochat/pkg/httpserver"
+	"github.com/Meystergod/gochat/pkg/mailer"
+	"github.com/Meystergod/gochat/pkg/metrics"
+	"github.com/Meystergod/gochat/pkg/migrate"
 	"github.com/Meystergod/gochat/pkg/ossignal"
+	"github.com/Meystergod/gochat/pkg/ratelimit"
+	"github.com/Meystergod/gochat/pkg/token"
+	"github.com/Meystergod/gochat/pkg/tracing"
 
+	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"golang.org/x/sync/errgroup"
 )
 
+// Deadlines of the shutdown phases, which run one after the other.
+const (
+	hubShutdownTimeout    = 10 * time.Second
+	httpShutdownTimeout   = 10 * time.Second
+	tracerShutdownTimeout = 5 * time.Second
+)
+
 type Application struct {
 	cfg        *config.Config
 	httpServer *httpserver.Server
 	db         *mongo.Database
+	hub        *realtime.Hub
+	broker     broker.Broker
+	migrator   *migrate.Migrator
+	health     *health.Health
+	registry   *prometheus.Registry
+	business   *businessMetrics
+	tracer     *sdktrace.TracerProvider
+
+	// settings that are applied again on every configuration reload
+	applied        *config.Config
+	rateLimits     *httpecho.RateLimits
+	corsOrigins    *httpserver.Origins
+	wsOrigins      *httpserver.Origins
+	requireIfMatch *atomic.Bool
 }
 
 func NewApplication(ctx context.Context, cfg *config.Config) (*Application, error) {
-	dbConfig := client.NewMongoConfig(
-		cfg.Database.Auth,
-		cfg.Database.Username,
-		cfg.Database.Password,
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.Name,
-	)
+	dbConfig := &client.MongoConfig{
+		URI:                    cfg.Database.URI,
+		Hosts:                  cfg.Database.Hosts,
+		Host:                   cfg.Database.Host,
+		Port:                   cfg.Database.Port,
+		ReplicaSet:             cfg.Database.ReplicaSet,
+		DatabaseName:           cfg.Database.Name,
+		AuthSource:             cfg.Database.Auth,
+		AuthMechanism:          cfg.Database.AuthMechanism,
+		Username:               cfg.Database.Username,
+		Password:               cfg.Database.Password,
+		TLS:                    cfg.Database.TLS,
+		TLSCAFile:              cfg.Database.TLSCAFile,
+		TLSCertKeyFile:         cfg.Database.TLSCertKeyFile,
+		TLSInsecure:            cfg.Database.TLSInsecure,
+		MinPoolSize:            uint64(cfg.Database.MinPoolSize),
+		MaxPoolSize:            uint64(cfg.Database.MaxPoolSize),
+		MaxConnIdleTime:        cfg.Database.MaxConnIdleTime,
+		ConnectTimeout:         cfg.Database.ConnectTimeout,
+		ServerSelectionTimeout: cfg.Database.ServerSelectionTimeout,
+		ReadPreference:         cfg.Database.ReadPreference,
+		ReadConcern:            cfg.Database.ReadConcern,
+		WriteConcern:           cfg.Database.WriteConcern,
+		RetryWrites:            cfg.Database.RetryWrites,
+		ConnectAttempts:        cfg.Database.ConnectAttempts,
+		ConnectBackoff:         cfg.Database.ConnectBackoff,
+	}
+
+	tracerProvider, err := tracing.NewTracerProvider(ctx, &tracing.Deps{
+		Exporter:       cfg.Tracing.Exporter,
+		ServiceName:    cfg.Application.Name,
+		ServiceVersion: cfg.Application.Version,
+		OTLPEndpoint:   cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure:   cfg.Tracing.OTLPInsecure,
+		SampleRatio:    cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating tracer provider")
+	}
+
+	// the usecases and repositories take their tracers from the global provider
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	dbConfig.TracerProvider = tracerProvider
+
+	registry := metrics.NewRegistry(cfg.Application.Name, cfg.Application.Version)
+
+	if cfg.Metrics.Enabled {
+		mongoMetrics := metrics.NewMongoMetrics(registry)
+		dbConfig.CommandMonitor = mongoMetrics.CommandMonitor()
+		dbConfig.PoolMonitor = mongoMetrics.PoolMonitor()
+	}
 
 	db, err := client.NewMongoDatabase(ctx, dbConfig)
 	if err != nil {
 		return nil, errors.Wrap(err, "connecting database")
 	}
 
+	eventBroker, err := broker.NewBroker(ctx, &broker.Deps{
+		Driver:     cfg.Broker.Driver,
+		Database:   db,
+		Collection: utils.CollNameEvent,
+		EventTTL:   cfg.Broker.EventTTL,
+		Buffer:     cfg.Broker.Buffer,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating event broker")
+	}
+
+	migrator, err := newMigrator(cfg, db)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating migrator")
+	}
+
+	rateLimitStore, err := ratelimit.NewStore(&ratelimit.Deps{
+		Driver:     cfg.RateLimit.Driver,
+		Database:   db,
+		Collection: utils.CollNameRateLimit,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating rate limit store")
+	}
+
+	rateLimits, err := httpecho.NewRateLimits(ctx, rateLimitStore, rateLimitPolicies(cfg), cfg.RateLimit.Enabled)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating rate limits")
+	}
+
+	requireIfMatch := &atomic.Bool{}
+	requireIfMatch.Store(cfg.HTTPServer.RequireIfMatch)
+
+	// the server is created before Run starts its goroutines, because the
+	// shutdown goroutine uses it as well
+	httpServer, err := httpserver.NewDefaultServer(&httpserver.ServerDeps{
+		Host:           cfg.HTTPServer.Host,
+		Port:           cfg.HTTPServer.Port,
+		ReadTimeout:    cfg.HTTPServer.ReadTimeout,
+		WriteTimeout:   cfg.HTTPServer.WriteTimeout,
+		TrustedProxies: cfg.HTTPServer.TrustedProxies,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating http server")
+	}
+
 	return &Application{
 		cfg:        cfg,
-		httpServer: nil,
+		httpServer: httpServer,
 		db:         db,
+		hub:        realtime.NewHub(),
+		broker:     eventBroker,
+		migrator:   migrator,
+		health:     newHealth(cfg, db, migrator),
+		registry:   registry,
+		business:   newBusinessMetrics(registry),
+		tracer:     tracerProvider,
+
+		applied:        cfg,
+		rateLimits:     rateLimits,
+		corsOrigins:    httpserver.NewOrigins(cfg.HTTPServer.CORSAllowedOrigins),
+		wsOrigins:      httpserver.NewOrigins(cfg.WebSocket.AllowedOrigins),
+		requireIfMatch: requireIfMatch,
 	}, nil
 }
 
 func (a *Application) Run(ctx context.Context) error {
 	logger := zerolog.Ctx(ctx)
 
+	if a.cfg.Database.MigrateOnStart {
+		if err := a.migrate(ctx); err != nil {
+			return errors.Wrap(err, "migrating database")
+		}
+	}
+
 	runner, ctx := errgroup.WithContext(ctx)
 
 	runner.Go(func() error {
@@ -63,6 +219,32 @@ func (a *Application) Run(ctx context.Context) error {
 		return nil
 	})
 
+	if a.cfg.Metrics.Enabled && a.cfg.Metrics.Addr != "" {
+		runner.Go(func() error {
+			if err := a.startMetrics(ctx); err != nil {
+				return errors.Wrap(err, "listening and starting metrics")
+			}
+
+			return nil
+		})
+	}
+
+	runner.Go(func() error {
+		if err := a.broker.Subscribe(ctx, a.hub.Publish); err != nil {
+			return errors.Wrap(err, "subscribing to event broker")
+		}
+
+		return nil
+	})
+
+	runner.Go(func() error {
+		if err := a.purgeDeletedUsers(ctx); err != nil {
+			return errors.Wrap(err, "purging deleted users")
+		}
+
+		return nil
+	})
+
 	runner.Go(func() error {
 		if err := ossignal.DefaultSignalWaiter(ctx); err != nil {
 			return errors.Wrap(err, "waiting os signal")
@@ -71,20 +253,47 @@ func (a *Application) Run(ctx context.Context) error {
 		return nil
 	})
 
+	runner.Go(func() error {
+		if err := ossignal.HandleReload(ctx, ossignal.DefaultReloadSignals(), a.reload); err != nil {
+			return errors.Wrap(err, "handling reload signals")
+		}
+
+		return nil
+	})
+
 	runner.Go(func() error {
 		<-ctx.Done()
 
-		ctxSignal, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		// fail readiness first and give load balancers time to notice before
+		// connections start being refused
+		a.health.SetDraining()
 
-		defer cancel()
+		if delay := a.cfg.Health.DrainDelay; delay > 0 {
+			logger.Info().Dur("delay", delay).Msg("wait for load balancers to stop routing")
+			time.Sleep(delay)
+		}
+
+		// every phase gets its own deadline, so that a slow drain does not
+		// leave the http server or the trace exporter without time
+		logger.Info().Msg("drain real-time subscribers")
+
+		if err := shutdownPhase(hubShutdownTimeout, a.hub.Shutdown); err != nil {
+			logger.Error().Err(err).Msg("drain real-time subscribers")
+		}
 
 		addr := fmt.Sprintf("%s:%s", a.cfg.HTTPServer.Host, a.cfg.HTTPServer.Port)
 		logger.Info().Str("addr", addr).Msg("shutdown http server")
 
-		if err := a.shutdownHTTP(ctxSignal); err != nil {
+		if err := shutdownPhase(httpShutdownTimeout, a.shutdownHTTP); err != nil {
 			logger.Error().Err(err).Msg("shutdown http server")
 		}
 
+		logger.Info().Msg("flush traces")
+
+		if err := shutdownPhase(tracerShutdownTimeout, a.tracer.Shutdown); err != nil {
+			logger.Error().Err(err).Msg("flush traces")
+		}
+
 		return nil
 	})
 
@@ -103,29 +312,175 @@ func (a *Application) Run(ctx context.Context) error {
 func (a *Application) startHTTP(ctx context.Context) error {
 	logger := zerolog.Ctx(ctx)
 
-	httpServerDeps := &httpserver.ServerDeps{
-		Host: a.cfg.HTTPServer.Host,
-		Port: a.cfg.HTTPServer.Port,
+	a.httpServer.Server().HTTPErrorHandler = apperror.HTTPAppErrorHandler(ctx, a.httpServer)
+
+	a.httpServer.Server().Validator = utils.NewValidator()
+
+	a.httpServer.Server().Use(tracing.NewHTTPTracing(&tracing.HTTPTracingDeps{
+		TracerProvider: a.tracer,
+		Propagator:     otel.GetTextMapPropagator(),
+		Logger:         logger,
+	}).Middleware())
+	a.httpServer.Server().Use(httpecho.RequestLoggerMiddleware(logger))
+	a.httpServer.Server().Use(httpecho.CORSMiddleware(a.corsOrigins))
+
+	if a.cfg.Metrics.Enabled {
+		a.httpServer.Server().Use(metrics.NewHTTPMetrics(a.registry).Middleware())
+
+		if a.cfg.Metrics.Addr == "" {
+			a.httpServer.Server().GET(a.cfg.Metrics.Path, echo.WrapHandler(metrics.Handler(a.registry)))
+			logger.Debug().Msg("set metrics route")
+		}
 	}
 
-	a.httpServer = httpserver.NewDefaultServer(httpServerDeps)
-	logger.Debug().Msg("created new http server")
+	a.httpServer.Server().Use(httpecho.ErrorResponseMiddleware())
 
-	a.httpServer.Server().HTTPErrorHandler = apperror.HTTPAppErrorHandler(ctx, a.httpServer)
+	httpecho.SetHealthApiRoutes(a.httpServer.Server(), controller.NewHealthController(a.health))
+	logger.Debug().Msg("set api routes for health")
 
-	a.httpServer.Server().Validator = utils.NewValidator()
+	rateLimits := a.rateLimits
 
 	userRepository := repository_user.NewUserRepository(a.db, utils.CollNameUser)
-	userUsecase := usecase_user.NewUserUsecase(userRepository)
+
+	passwordHasher, err := hasher.NewBcryptHasher(&hasher.BcryptHasherDeps{
+		Cost: a.cfg.Security.PasswordHashCost,
+	})
+	if err != nil {
+		return errors.Wrap(err, "creating password hasher")
+	}
+
+	userMailer, err := mailer.NewMailer(&mailer.Deps{
+		Driver:       a.cfg.Mail.Driver,
+		From:         a.cfg.Mail.From,
+		SMTPHost:     a.cfg.Mail.SMTPHost,
+		SMTPPort:     a.cfg.Mail.SMTPPort,
+		SMTPUsername: a.cfg.Mail.SMTPUsername,
+		SMTPPassword: a.cfg.Mail.SMTPPassword,
+		LogFile:      a.cfg.Mail.LogFile,
+	})
+	if err != nil {
+		return errors.Wrap(err, "creating mailer")
+	}
+
+	userTokenRepository := repository_token.NewUserTokenRepository(a.db, utils.CollNameToken)
+	verificationUsecase := usecase_verification.NewVerificationUsecase(&usecase_verification.VerificationUsecaseDeps{
+		UserTokenRepository: userTokenRepository,
+		UserRepository:      userRepository,
+		Mailer:              userMailer,
+		TokenTTL:            a.cfg.Users.VerificationTokenTTL,
+		VerifyURL:           a.cfg.Users.VerifyURL,
+	})
+	verificationController := controller.NewVerificationController(verificationUsecase)
+
+	sessionRepository := repository_session.NewSessionRepository(a.db, utils.CollNameSession)
+	userUsecase, err := usecase_user.NewUserUsecase(&usecase_user.UserUsecaseDeps{
+		UserRepository:     userRepository,
+		PasswordHasher:     passwordHasher,
+		VerificationSender: verificationUsecase,
+		SessionRevoker:     sessionRepository,
+		MetricsRecorder:    a.business,
+	})
+	if err != nil {
+		return errors.Wrap(err, "creating user usecase")
+	}
 	userController := controller.NewUserController(userUsecase)
 
-	httpecho.SetUserApiRoutes(a.httpServer.Server(), userController)
+	accessTokenManager, err := token.NewJWTManager(&token.JWTManagerDeps{
+		SigningKey: a.cfg.Auth.SigningKey,
+		Issuer:     a.cfg.Auth.Issuer,
+		TTL:        a.cfg.Auth.AccessTokenTTL,
+	})
+	if err != nil {
+		return errors.Wrap(err, "creating access token manager")
+	}
+
+	authUsecase := usecase_auth.NewAuthUsecase(&usecase_auth.AuthUsecaseDeps{
+		SessionRepository: sessionRepository,
+		UserProvider:      userUsecase,
+		AccessTokenIssuer: accessTokenManager,
+		RefreshTTL:        a.cfg.Auth.RefreshTokenTTL,
+	})
+	authController := controller.NewAuthController(authUsecase)
+	authMiddleware := httpecho.AuthMiddleware(accessTokenManager)
+
+	httpecho.SetUserApiRoutes(a.httpServer.Server(), userController, authMiddleware, rateLimits, a.requireIfMatch)
 	logger.Debug().Msg("set api routes for user")
 
+	httpecho.SetAuthApiRoutes(a.httpServer.Server(), authController, rateLimits)
+	logger.Debug().Msg("set api routes for auth")
+
+	httpecho.SetVerificationApiRoutes(a.httpServer.Server(), verificationController, rateLimits)
+	logger.Debug().Msg("set api routes for verification")
+
+	passwordUsecase := usecase_password.NewPasswordUsecase(&usecase_password.PasswordUsecaseDeps{
+		UserTokenRepository: userTokenRepository,
+		UserRepository:      userRepository,
+		SessionRevoker:      sessionRepository,
+		PasswordHasher:      passwordHasher,
+		Mailer:              userMailer,
+		TokenTTL:            a.cfg.Users.PasswordResetTokenTTL,
+		ResetURL:            a.cfg.Users.PasswordResetURL,
+	})
+	passwordController := controller.NewPasswordController(passwordUsecase)
+
+	httpecho.SetPasswordApiRoutes(a.httpServer.Server(), passwordController, rateLimits)
+	logger.Debug().Msg("set api routes for password")
+
+	roomRepository := repository_room.NewRoomRepository(a.db, utils.CollNameRoom)
+	messageRepository := repository_message.NewMessageRepository(a.db, utils.CollNameMessage)
+	roomUsecase := usecase_room.NewRoomUsecase(&usecase_room.RoomUsecaseDeps{
+		RoomRepository: roomRepository,
+		MessageRemover: messageRepository,
+	})
+	roomController := controller.NewRoomController(roomUsecase)
+
+	messageUsecase := usecase_message.NewMessageUsecase(&usecase_message.MessageUsecaseDeps{
+		MessageRepository: messageRepository,
+		RoomProvider:      roomUsecase,
+		EventPublisher:    a.broker,
+		MetricsRecorder:   a.business,
+	})
+	messageController := controller.NewMessageController(messageUsecase)
+	eventController := controller.NewEventController(&controller.EventControllerDeps{
+		Hub:               a.hub,
+		RoomUsecase:       roomUsecase,
+		MessageUsecase:    messageUsecase,
+		Buffer:            a.cfg.SSE.Buffer,
+		KeepAliveInterval: a.cfg.SSE.KeepAliveInterval,
+		WriteWait:         a.cfg.SSE.WriteWait,
+		ReplayLimit:       a.cfg.SSE.ReplayLimit,
+	})
+
+	httpecho.SetRoomApiRoutes(a.httpServer.Server(), roomController, eventController, authMiddleware, rateLimits)
+	logger.Debug().Msg("set api routes for room")
+
+	httpecho.SetMessageApiRoutes(a.httpServer.Server(), messageController, authMiddleware, rateLimits)
+	logger.Debug().Msg("set api routes for message")
+
+	wsController := controller.NewWebSocketController(&controller.WebSocketControllerDeps{
+		Hub:    a.hub,
+		Broker: a.broker,
+		Upgrader: httpserver.NewWebSocketUpgrader(&httpserver.WebSocketDeps{
+			AllowedOrigins: a.wsOrigins,
+		}),
+		ClientConfig: realtime.ClientConfig{
+			SendBuffer:     a.cfg.WebSocket.SendBuffer,
+			PingInterval:   a.cfg.WebSocket.PingInterval,
+			PongWait:       a.cfg.WebSocket.PongWait,
+			WriteWait:      a.cfg.WebSocket.WriteWait,
+			MaxMessageSize: a.cfg.WebSocket.MaxMessageSize,
+		},
+		RoomUsecase:    roomUsecase,
+		MessageUsecase: messageUsecase,
+	})
+
+	httpecho.SetWebSocketApiRoutes(a.httpServer.Server(), wsController, httpecho.WebSocketAuthMiddleware(accessTokenManager), rateLimits)
+	logger.Debug().Msg("set api routes for websocket")
+
 	addr := fmt.Sprintf("%s:%s", a.cfg.HTTPServer.Host, a.cfg.HTTPServer.Port)
 	logger.Info().Str("addr", addr).Msg("listen and serve http api")
 
-	err := a.httpServer.Start()
+	err = a.httpServer.Start()
 	if err != nil {
 		return errors.Wrap(err, "starting http server error")
 	}
@@ -133,10 +488,14 @@ func (a *Application) startHTTP(ctx context.Context) error {
 	return nil
 }
 
-func (a *Application) shutdownHTTP(ctx context.Context) error {
-	return a.httpServer.Shutdown(ctx)
+func shutdownPhase(timeout time.Duration, shutdown func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	defer cancel()
+
+	return shutdown(ctx)
 }
 
-func (a *Application) migrate(_ context.Context) error {
-	return nil
+func (a *Application) shutdownHTTP(ctx context.Context) error {
+	return a.httpServer.Shutdown(ctx)
 }
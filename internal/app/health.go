@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+
+	"github.com/Meystergod/gochat/internal/config"
+	"github.com/Meystergod/gochat/pkg/health"
+	"github.com/Meystergod/gochat/pkg/migrate"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func newHealth(cfg *config.Config, db *mongo.Database, migrator *migrate.Migrator) *health.Health {
+	return health.NewHealth(&health.HealthDeps{
+		Timeout: cfg.Health.CheckTimeout,
+		Checks: []health.Check{
+			{
+				Name: "mongo",
+				Check: func(ctx context.Context) error {
+					return db.Client().Ping(ctx, readpref.Primary())
+				},
+			},
+			{
+				Name: "migrations",
+				Check: func(ctx context.Context) error {
+					pending, err := migrator.Pending(ctx)
+					if err != nil {
+						return err
+					}
+
+					if pending > 0 {
+						return errors.Errorf("%d migration(s) pending", pending)
+					}
+
+					return nil
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Meystergod/gochat/pkg/metrics"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+// businessMetrics counts domain events for the usecases.
+type businessMetrics struct {
+	signups      prometheus.Counter
+	messagesSent prometheus.Counter
+}
+
+func newBusinessMetrics(registerer prometheus.Registerer) *businessMetrics {
+	m := &businessMetrics{
+		signups: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gochat_signups_total",
+			Help: "Number of registered accounts.",
+		}),
+		messagesSent: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gochat_messages_sent_total",
+			Help: "Number of messages sent to rooms.",
+		}),
+	}
+
+	registerer.MustRegister(m.signups, m.messagesSent)
+
+	return m
+}
+
+func (m *businessMetrics) RecordSignup() {
+	m.signups.Inc()
+}
+
+func (m *businessMetrics) RecordMessageSent() {
+	m.messagesSent.Inc()
+}
+
+// startMetrics serves the metrics on a dedicated admin address, which keeps
+// them off the public listener. It returns once ctx is done and the server is
+// shut down.
+func (a *Application) startMetrics(ctx context.Context) error {
+	logger := zerolog.Ctx(ctx)
+
+	mux := http.NewServeMux()
+	mux.Handle(a.cfg.Metrics.Path, metrics.Handler(a.registry))
+
+	server := &http.Server{
+		Addr:              a.cfg.Metrics.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: a.cfg.HTTPServer.ReadTimeout,
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		logger.Info().Str("addr", a.cfg.Metrics.Addr).Msg("listen and serve metrics")
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return errors.Wrap(err, "serving metrics")
+	case <-ctx.Done():
+	}
+
+	ctxShutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	defer cancel()
+
+	if err := server.Shutdown(ctxShutdown); err != nil {
+		return errors.Wrap(err, "shutdown metrics server")
+	}
+
+	return nil
+}
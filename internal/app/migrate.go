@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Meystergod/gochat/internal/config"
+	"github.com/Meystergod/gochat/internal/migrations"
+	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/migrate"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newMigrator(cfg *config.Config, db *mongo.Database) (*migrate.Migrator, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	migratorDeps := &migrate.MigratorDeps{
+		Database:       db,
+		Collection:     utils.CollNameMigration,
+		LockCollection: utils.CollNameMigrationLock,
+		LockTTL:        cfg.Database.MigrationLockTTL,
+		LockTimeout:    cfg.Database.MigrationLockTimeout,
+		Owner:          fmt.Sprintf("%s/%d/%s", hostname, os.Getpid(), uuid.NewString()),
+	}
+
+	return migrate.NewMigrator(migratorDeps, migrations.All())
+}
+
+// migrate applies pending migrations on startup.
+func (a *Application) migrate(ctx context.Context) error {
+	_, err := a.MigrateUp(ctx)
+	return err
+}
+
+func (a *Application) MigrateUp(ctx context.Context) ([]migrate.Migration, error) {
+	logger := zerolog.Ctx(ctx)
+
+	applied, err := a.migrator.Up(ctx)
+	for _, migration := range applied {
+		logger.Info().Uint64("version", migration.Version).Str("name", migration.Name).Msg("applied migration")
+	}
+
+	return applied, err
+}
+
+func (a *Application) MigrateDown(ctx context.Context, steps int) ([]migrate.Migration, error) {
+	logger := zerolog.Ctx(ctx)
+
+	reverted, err := a.migrator.Down(ctx, steps)
+	for _, migration := range reverted {
+		logger.Info().Uint64("version", migration.Version).Str("name", migration.Name).Msg("reverted migration")
+	}
+
+	return reverted, err
+}
+
+func (a *Application) MigrationStatus(ctx context.Context) ([]migrate.Status, error) {
+	return a.migrator.Status(ctx)
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/repository/repository_user/mongodb"
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/rs/zerolog"
+)
+
+// purgeDeletedUsers periodically removes accounts that have been soft deleted
+// for longer than the configured retention window. A zero retention or
+// interval keeps deleted accounts forever.
+func (a *Application) purgeDeletedUsers(ctx context.Context) error {
+	logger := zerolog.Ctx(ctx)
+
+	retention := a.cfg.Users.DeletedRetention
+	interval := a.cfg.Users.PurgeInterval
+
+	if retention == 0 || interval == 0 {
+		logger.Info().Msg("purging deleted users is disabled")
+		return nil
+	}
+
+	userRepository := repository_user.NewUserRepository(a.db, utils.CollNameUser)
+
+	ticker := time.NewTicker(interval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			purged, err := userRepository.PurgeDeletedUsers(ctx, time.Now().Add(-retention))
+			if err != nil {
+				logger.Error().Err(err).Msg("purge deleted users")
+				continue
+			}
+
+			if purged > 0 {
+				logger.Info().Int64("count", purged).Msg("purged deleted users")
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Meystergod/gochat/internal/config"
+	"github.com/Meystergod/gochat/internal/delivery/http/v1/httpecho"
+	"github.com/Meystergod/gochat/pkg/ratelimit"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// reload reads the configuration again and applies the settings that can
+// change while running. A change of any other setting rejects the whole
+// reload, so that the running configuration is never half applied.
+func (a *Application) reload(ctx context.Context) error {
+	logger := zerolog.Ctx(ctx)
+
+	next, err := a.applied.Reload()
+	if err != nil {
+		return errors.Wrap(err, "reading configuration")
+	}
+
+	changed, restart := a.applied.Diff(next)
+	if len(restart) > 0 {
+		return errors.Errorf("changing %s requires a restart", strings.Join(restart, ", "))
+	}
+
+	if len(changed) == 0 {
+		logger.Info().Msg("configuration unchanged")
+		return nil
+	}
+
+	level, err := zerolog.ParseLevel(next.Log.LogLevel)
+	if err != nil {
+		return errors.Wrap(err, "parsing log level")
+	}
+
+	if err = a.rateLimits.Update(rateLimitPolicies(next), next.RateLimit.Enabled); err != nil {
+		return errors.Wrap(err, "updating rate limits")
+	}
+
+	zerolog.SetGlobalLevel(level)
+
+	a.corsOrigins.Set(next.HTTPServer.CORSAllowedOrigins)
+	a.wsOrigins.Set(next.WebSocket.AllowedOrigins)
+	a.requireIfMatch.Store(next.HTTPServer.RequireIfMatch)
+
+	a.applied = next
+
+	logger.Info().Strs("keys", changed).Msg("applied configuration changes")
+
+	return nil
+}
+
+func rateLimitPolicies(cfg *config.Config) *httpecho.RateLimitPolicies {
+	return &httpecho.RateLimitPolicies{
+		Strict: ratelimit.Policy{Name: "strict", Limit: cfg.RateLimit.StrictLimit, Period: cfg.RateLimit.StrictPeriod},
+		Read:   ratelimit.Policy{Name: "read", Limit: cfg.RateLimit.ReadLimit, Period: cfg.RateLimit.ReadPeriod},
+		Write:  ratelimit.Policy{Name: "write", Limit: cfg.RateLimit.WriteLimit, Period: cfg.RateLimit.WritePeriod},
+	}
+}
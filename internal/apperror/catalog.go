@@ -0,0 +1,83 @@
+package apperror
+
+import (
+	"net/http"
+)
+
+// Kind is a catalogued error with a stable machine-readable code and the
+// http status it is answered with. A kind may refine a more general parent,
+// so that errors.Is(ErrorUserNotFound, ErrorNotFound) holds.
+type Kind struct {
+	code   string
+	status int
+	text   string
+	parent *Kind
+}
+
+func newKind(code string, status int, text string) *Kind {
+	return &Kind{code: code, status: status, text: text}
+}
+
+func (k *Kind) refine(code, text string) *Kind {
+	return &Kind{code: code, status: k.status, text: text, parent: k}
+}
+
+func (k *Kind) Error() string {
+	return k.text
+}
+
+func (k *Kind) Unwrap() error {
+	if k.parent == nil {
+		return nil
+	}
+
+	return k.parent
+}
+
+func (k *Kind) Code() string {
+	return k.code
+}
+
+func (k *Kind) Status() int {
+	return k.status
+}
+
+var (
+	ErrorDecode        = newKind("internal.decode", http.StatusInternalServerError, "failed to decode")
+	ErrorConvert       = newKind("internal.convert", http.StatusInternalServerError, "failed to convert")
+	ErrorConvertModel  = newKind("internal.convert_model", http.StatusInternalServerError, "failed to convert domain model to repository model")
+	ErrorHashPassword  = newKind("internal.hash_password", http.StatusInternalServerError, "failed to hash password")
+	ErrorGenerateToken = newKind("internal.generate_token", http.StatusInternalServerError, "failed to generate token")
+	ErrorInternal      = newKind("internal.error", http.StatusInternalServerError, "internal server error")
+
+	ErrorCreateOne = newKind("database.create", http.StatusInternalServerError, "failed to insert object into database")
+	ErrorGetOne    = newKind("database.get", http.StatusInternalServerError, "failed to get object from database")
+	ErrorGetAll    = newKind("database.list", http.StatusInternalServerError, "failed to get all objects from database")
+	ErrorUpdateOne = newKind("database.update", http.StatusInternalServerError, "failed to update object in database")
+	ErrorDeleteOne = newKind("database.delete", http.StatusInternalServerError, "failed to delete object from database")
+
+	ErrorMalformedPayload = newKind("request.malformed", http.StatusBadRequest, "failed to bind payload value")
+	ErrorGetUrlParams     = newKind("request.invalid_param", http.StatusBadRequest, "failed to get param from query url")
+	ErrorInvalidID        = newKind("request.invalid_id", http.StatusBadRequest, "invalid object id")
+	ErrorRateLimited      = newKind("request.rate_limited", http.StatusTooManyRequests, "too many requests")
+	ErrorValidatePayload  = newKind("validation.failed", http.StatusUnprocessableEntity, "failed to validate payload value")
+
+	ErrorNotFound        = newKind("not_found", http.StatusNotFound, "object not found in database")
+	ErrorUserNotFound    = ErrorNotFound.refine("user.not_found", "user not found")
+	ErrorRoomNotFound    = ErrorNotFound.refine("room.not_found", "room not found")
+	ErrorMessageNotFound = ErrorNotFound.refine("message.not_found", "message not found")
+
+	ErrorConflict       = newKind("conflict", http.StatusConflict, "object state conflict")
+	ErrorUserEmailTaken = ErrorConflict.refine("user.email_taken", "email is already taken")
+	ErrorRoomArchived   = ErrorConflict.refine("room.archived", "room is archived")
+
+	ErrorPreconditionFailed   = newKind("precondition.failed", http.StatusPreconditionFailed, "precondition failed")
+	ErrorUserVersionMismatch  = ErrorPreconditionFailed.refine("user.version_mismatch", "user has been modified")
+	ErrorPreconditionRequired = newKind("precondition.required", http.StatusPreconditionRequired, "precondition required")
+
+	ErrorCredentials  = newKind("auth.invalid_credentials", http.StatusUnauthorized, "invalid credentials")
+	ErrorToken        = newKind("auth.invalid_token", http.StatusUnauthorized, "invalid or expired token")
+	ErrorUnauthorized = newKind("auth.required", http.StatusUnauthorized, "authentication required")
+	ErrorForbidden    = newKind("auth.forbidden", http.StatusForbidden, "access forbidden")
+	ErrorUnverified   = ErrorForbidden.refine("user.unverified", "email address is not verified")
+)
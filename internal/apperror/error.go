@@ -2,32 +2,25 @@ package apperror
 
 import (
 	"context"
+	"encoding/xml"
 	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/Meystergod/gochat/internal/utils"
 	"github.com/Meystergod/gochat/pkg/httpserver"
 
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog"
 )
 
-var (
-	ErrorDecode          = errors.New("failed to decode")
-	ErrorConvert         = errors.New("failed to convert")
-	ErrorConvertModel    = errors.New("failed to convert domain model to repository model")
-	ErrorCreateOne       = errors.New("failed to insert object into database")
-	ErrorGetOne          = errors.New("failed to get object from database")
-	ErrorGetAll          = errors.New("failed to get all objects from database")
-	ErrorUpdateOne       = errors.New("failed to update object in database")
-	ErrorDeleteOne       = errors.New("failed to delete object from database")
-	ErrorValidatePayload = errors.New("failed to validate or bind payload value")
-	ErrorGetUrlParams    = errors.New("failed to get param from query url")
-)
-
 type AppError struct {
-	Err          error  `json:"-"`
-	ErrorMessage string `json:"error"`
-	Message      string `json:"message"`
+	XMLName      xml.Name     `json:"-" xml:"error"`
+	Err          error        `json:"-" xml:"-"`
+	Code         string       `json:"code" xml:"code"`
+	ErrorMessage string       `json:"error" xml:"description"`
+	Message      string       `json:"message" xml:"message"`
+	Details      []FieldError `json:"details,omitempty" xml:"detail,omitempty"`
 }
 
 func NewAppError(err error, message string) *AppError {
@@ -41,6 +34,21 @@ func (e *AppError) Error() string {
 	return e.Err.Error()
 }
 
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// Kind returns the catalogued kind of the error, falling back to
+// ErrorInternal for errors that are not part of the catalog.
+func (e *AppError) Kind() *Kind {
+	var kind *Kind
+	if errors.As(e.Err, &kind) {
+		return kind
+	}
+
+	return ErrorInternal
+}
+
 func HTTPAppErrorHandler(ctx context.Context, server *httpserver.Server) func(err error, c echo.Context) {
 	logger := zerolog.Ctx(ctx)
 
@@ -49,32 +57,73 @@ func HTTPAppErrorHandler(ctx context.Context, server *httpserver.Server) func(er
 			return
 		}
 
-		var appError *AppError
-		if errors.As(err, &appError) {
-			switch {
-			case errors.Is(appError.Err, ErrorDecode),
-				errors.Is(appError.Err, ErrorCreateOne),
-				errors.Is(appError.Err, ErrorConvert),
-				errors.Is(appError.Err, ErrorGetOne),
-				errors.Is(appError.Err, ErrorGetAll),
-				errors.Is(appError.Err, ErrorUpdateOne),
-				errors.Is(appError.Err, ErrorDeleteOne),
-				errors.Is(appError.Err, ErrorConvertModel):
-
-				appError.ErrorMessage = appError.Error()
-				if jsonError := c.JSON(http.StatusInternalServerError, &appError); jsonError != nil {
-					logger.Error().Msgf("failed to create json response: %s", jsonError.Error())
-				}
-				return
-			case errors.Is(appError.Err, ErrorValidatePayload):
-				appError.ErrorMessage = appError.Error()
-				if jsonError := c.JSON(http.StatusBadRequest, &appError); jsonError != nil {
-					logger.Error().Msgf("failed to validate json response: %s", jsonError.Error())
-				}
-				return
+		appError, status := toAppError(err)
+
+		if status >= http.StatusInternalServerError {
+			requestLogger := zerolog.Ctx(c.Request().Context())
+			if requestLogger.GetLevel() == zerolog.Disabled {
+				requestLogger = logger
+			}
+
+			requestLogger.Error().
+				Err(err).
+				Str("code", appError.Code).
+				Str("cause", appError.Message).
+				Msg("failed to serve http request")
+
+			// the cause of a server error names internals such as queries and
+			// hosts, so it is only logged and the client gets the kind
+			appError = &AppError{
+				Err:          appError.Err,
+				Code:         appError.Code,
+				ErrorMessage: appError.ErrorMessage,
+				Message:      appError.ErrorMessage,
 			}
 		}
 
-		server.Server().DefaultHTTPErrorHandler(err, c)
+		if c.Request().Method == http.MethodHead {
+			err = c.NoContent(status)
+		} else {
+			err = utils.Negotiate(c, status, appError)
+		}
+
+		if err != nil {
+			logger.Error().Msgf("failed to create error response: %s", err.Error())
+			server.Server().DefaultHTTPErrorHandler(err, c)
+		}
+	}
+}
+
+func toAppError(err error) (*AppError, int) {
+	var appError *AppError
+	if errors.As(err, &appError) {
+		kind := appError.Kind()
+
+		appError.Code = kind.Code()
+		appError.ErrorMessage = appError.Error()
+
+		return appError, kind.Status()
 	}
+
+	var httpError *echo.HTTPError
+	if errors.As(err, &httpError) {
+		message, ok := httpError.Message.(string)
+		if !ok {
+			message = http.StatusText(httpError.Code)
+		}
+
+		return &AppError{
+			Err:          httpError,
+			Code:         "http." + strings.ReplaceAll(strings.ToLower(http.StatusText(httpError.Code)), " ", "_"),
+			ErrorMessage: http.StatusText(httpError.Code),
+			Message:      message,
+		}, httpError.Code
+	}
+
+	return &AppError{
+		Err:          err,
+		Code:         ErrorInternal.Code(),
+		ErrorMessage: ErrorInternal.Error(),
+		Message:      ErrorInternal.Error(),
+	}, ErrorInternal.Status()
 }
@@ -0,0 +1,74 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type FieldError struct {
+	Field   string `json:"field" xml:"field,attr"`
+	Rule    string `json:"rule" xml:"rule,attr"`
+	Param   string `json:"param,omitempty" xml:"param,attr,omitempty"`
+	Message string `json:"message" xml:",chardata"`
+}
+
+// NewValidationError converts an error returned by utils.BindAndValidate. A
+// payload that could not be bound is malformed, while a bound payload that
+// breaks validation rules is reported with per-field details.
+func NewValidationError(err error) *AppError {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		appError := NewAppError(ErrorValidatePayload, "payload has invalid fields")
+
+		for _, fieldError := range validationErrors {
+			appError.Details = append(appError.Details, FieldError{
+				Field:   fieldError.Field(),
+				Rule:    fieldError.Tag(),
+				Param:   fieldError.Param(),
+				Message: fieldErrorMessage(fieldError),
+			})
+		}
+
+		return appError
+	}
+
+	var httpError *echo.HTTPError
+	if errors.As(err, &httpError) {
+		return NewAppError(ErrorMalformedPayload, fmt.Sprint(httpError.Message))
+	}
+
+	return NewAppError(ErrorMalformedPayload, err.Error())
+}
+
+// NewFieldValidationError reports payload fields rejected before struct
+// validation could run, using the same shape as NewValidationError.
+func NewFieldValidationError(details []FieldError) *AppError {
+	appError := NewAppError(ErrorValidatePayload, "payload has invalid fields")
+	appError.Details = details
+
+	return appError
+}
+
+func fieldErrorMessage(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "field is required"
+	case "email":
+		return "field must be a valid email address"
+	case "min":
+		return fmt.Sprintf("field must be at least %s characters long", fieldError.Param())
+	case "max":
+		return fmt.Sprintf("field must be at most %s characters long", fieldError.Param())
+	case "maxbytes":
+		return fmt.Sprintf("field must be at most %s bytes long", fieldError.Param())
+	case "oneof":
+		return fmt.Sprintf("field must be one of: %s", fieldError.Param())
+	case "mongodb":
+		return "field must be a valid object id"
+	default:
+		return fmt.Sprintf("field does not satisfy the %s rule", fieldError.Tag())
+	}
+}
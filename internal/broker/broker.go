@@ -0,0 +1,62 @@
+package broker
+
+import (
+	"context"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/domain"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	DriverMemory = "memory"
+	DriverMongo  = "mongo"
+)
+
+// Handler receives every event published to the broker, including events
+// published by other gochat instances.
+type Handler func(ctx context.Context, event *domain.Event) error
+
+// Broker fans room events out across gochat instances. Publishers hand events
+// to the broker instead of the local hub, and every instance runs Subscribe
+// to feed its hub.
+type Broker interface {
+	Publish(ctx context.Context, event *domain.Event) error
+	Subscribe(ctx context.Context, handler Handler) error
+}
+
+var (
+	ErrUnknownDriver = errors.New("unknown broker driver")
+	ErrNoReplicaSet  = errors.New("the mongo broker needs a replica set or sharded cluster for change streams")
+)
+
+type Deps struct {
+	Driver     string
+	Database   *mongo.Database
+	Collection string
+	EventTTL   time.Duration
+	Buffer     int
+}
+
+func NewBroker(ctx context.Context, deps *Deps) (Broker, error) {
+	switch deps.Driver {
+	case DriverMemory:
+		return NewMemoryBroker(deps.Buffer), nil
+	case DriverMongo:
+		mongoBroker := NewMongoBroker(&MongoBrokerDeps{
+			Database:   deps.Database,
+			Collection: deps.Collection,
+			EventTTL:   deps.EventTTL,
+		})
+
+		if err := mongoBroker.CheckDeployment(ctx); err != nil {
+			return nil, err
+		}
+
+		return mongoBroker, nil
+	default:
+		return nil, errors.Wrap(ErrUnknownDriver, deps.Driver)
+	}
+}
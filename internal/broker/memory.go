@@ -0,0 +1,77 @@
+package broker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Meystergod/gochat/internal/domain"
+
+	"github.com/rs/zerolog"
+)
+
+// MemoryBroker delivers events within a single process. It is meant for
+// single-node deployments and tests.
+type MemoryBroker struct {
+	mu          sync.RWMutex
+	subscribers map[chan *domain.Event]struct{}
+	buffer      int
+}
+
+func NewMemoryBroker(buffer int) *MemoryBroker {
+	return &MemoryBroker{
+		subscribers: make(map[chan *domain.Event]struct{}),
+		buffer:      buffer,
+	}
+}
+
+// Publish hands the event to every subscriber without waiting. A subscriber
+// whose buffer is full misses the event rather than stalling the publisher.
+func (b *MemoryBroker) Publish(ctx context.Context, event *domain.Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	dropped := 0
+	for events := range b.subscribers {
+		select {
+		case events <- event:
+		default:
+			dropped++
+		}
+	}
+
+	if dropped > 0 {
+		zerolog.Ctx(ctx).Warn().
+			Str("room_id", event.RoomID).
+			Int("dropped", dropped).
+			Msg("dropped event for slow broker subscribers")
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, handler Handler) error {
+	logger := zerolog.Ctx(ctx)
+
+	events := make(chan *domain.Event, b.buffer)
+
+	b.mu.Lock()
+	b.subscribers[events] = struct{}{}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.subscribers, events)
+		b.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case event := <-events:
+			if err := handler(ctx, event); err != nil {
+				logger.Error().Err(err).Str("room_id", event.RoomID).Msg("handle broker event")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
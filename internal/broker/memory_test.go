@@ -0,0 +1,187 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/domain"
+)
+
+// subscribe runs Subscribe until the test ends and returns the channel the
+// handler forwards events to. handled blocks the handler until it is read
+// from, which lets a test hold a subscriber back.
+func subscribe(t *testing.T, b Broker) <-chan *domain.Event {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handled := make(chan *domain.Event)
+	stopped := make(chan error, 1)
+
+	go func() {
+		stopped <- b.Subscribe(ctx, func(ctx context.Context, event *domain.Event) error {
+			select {
+			case handled <- event:
+			case <-ctx.Done():
+			}
+			return nil
+		})
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+
+		if err := <-stopped; err != nil {
+			t.Errorf("subscribe: %v", err)
+		}
+	})
+
+	return handled
+}
+
+func waitSubscribers(t *testing.T, b *MemoryBroker, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for {
+		b.mu.RLock()
+		got := len(b.subscribers)
+		b.mu.RUnlock()
+
+		if got == want {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d subscribers, want %d", got, want)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func receive(t *testing.T, events <-chan *domain.Event) *domain.Event {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return nil
+	}
+}
+
+func TestMemoryBrokerFansOut(t *testing.T) {
+	b := NewMemoryBroker(4)
+
+	first := subscribe(t, b)
+	second := subscribe(t, b)
+	waitSubscribers(t, b, 2)
+
+	if err := b.Publish(context.Background(), &domain.Event{ID: "event-1", RoomID: "room"}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	for _, events := range []<-chan *domain.Event{first, second} {
+		if event := receive(t, events); event.ID != "event-1" {
+			t.Fatalf("got event %s, want event-1", event.ID)
+		}
+	}
+}
+
+func TestMemoryBrokerSlowSubscriberMissesEvents(t *testing.T) {
+	b := NewMemoryBroker(1)
+
+	slow := subscribe(t, b)
+	fast := subscribe(t, b)
+	waitSubscribers(t, b, 2)
+
+	// the slow handler holds event-1 and its buffer holds event-2, so event-3
+	// is dropped for it while the fast subscriber keeps up
+	for _, id := range []string{"event-1", "event-2", "event-3"} {
+		if err := b.Publish(context.Background(), &domain.Event{ID: id, RoomID: "room"}); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+
+		if event := receive(t, fast); event.ID != id {
+			t.Fatalf("fast subscriber got %s, want %s", event.ID, id)
+		}
+
+		if id == "event-1" {
+			waitBuffered(t, b, 0)
+		}
+	}
+
+	var got []string
+	for i := 0; i < 2; i++ {
+		got = append(got, receive(t, slow).ID)
+	}
+
+	if got[0] != "event-1" || got[1] != "event-2" {
+		t.Fatalf("slow subscriber got %v, want [event-1 event-2]", got)
+	}
+
+	select {
+	case event := <-slow:
+		t.Fatalf("slow subscriber got dropped event %s", event.ID)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// waitBuffered waits until every subscriber buffer holds want events.
+func waitBuffered(t *testing.T, b *MemoryBroker, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for {
+		done := true
+
+		b.mu.RLock()
+		for events := range b.subscribers {
+			if len(events) != want {
+				done = false
+			}
+		}
+		b.mu.RUnlock()
+
+		if done {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("subscriber buffers do not hold %d events", want)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMemoryBrokerUnsubscribesOnCancel(t *testing.T) {
+	b := NewMemoryBroker(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+
+	go func() {
+		stopped <- b.Subscribe(ctx, func(context.Context, *domain.Event) error { return nil })
+	}()
+
+	waitSubscribers(t, b, 1)
+	cancel()
+
+	if err := <-stopped; err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	waitSubscribers(t, b, 0)
+}
+
+func TestNewBrokerUnknownDriver(t *testing.T) {
+	if _, err := NewBroker(context.Background(), &Deps{Driver: "kafka"}); !errors.Is(err, ErrUnknownDriver) {
+		t.Fatalf("got %v, want %v", err, ErrUnknownDriver)
+	}
+}
@@ -0,0 +1,221 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/domain"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	mongoRetryMin = time.Second
+	mongoRetryMax = 30 * time.Second
+
+	eventTTLIndex = "created_at_ttl"
+
+	codeNamespaceNotFound = 26
+	codeIndexNotFound     = 27
+)
+
+type eventDocument struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	RoomID    string             `bson:"room_id"`
+	Payload   []byte             `bson:"payload"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+type helloDocument struct {
+	SetName string `bson:"setName"`
+	Msg     string `bson:"msg"`
+}
+
+type changeDocument struct {
+	FullDocument eventDocument `bson:"fullDocument"`
+}
+
+type MongoBrokerDeps struct {
+	Database   *mongo.Database
+	Collection string
+	EventTTL   time.Duration
+}
+
+// MongoBroker shares events between instances through a collection: events
+// are inserted by the publishing instance and every instance tails inserts
+// with a change stream. Change streams require a replica set or sharded
+// cluster.
+type MongoBroker struct {
+	collection *mongo.Collection
+	eventTTL   time.Duration
+}
+
+func NewMongoBroker(deps *MongoBrokerDeps) *MongoBroker {
+	return &MongoBroker{
+		collection: deps.Database.Collection(deps.Collection),
+		eventTTL:   deps.EventTTL,
+	}
+}
+
+// CheckDeployment fails with ErrNoReplicaSet on a standalone server. There the
+// change stream of Subscribe could never open and the broker would only retry
+// in the background, so the instance fails on startup instead.
+func (b *MongoBroker) CheckDeployment(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+
+	defer cancel()
+
+	var hello helloDocument
+
+	err := b.collection.Database().RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return errors.Wrap(err, "checking broker deployment")
+	}
+
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		return ErrNoReplicaSet
+	}
+
+	return nil
+}
+
+// applyEventTTL sets the expiry of the event index, which is created by the
+// database migrations, to the configured ttl. collMod changes the index in
+// place, so the ttl can be reconfigured without rebuilding it. The collection
+// only has to hold the window needed to resume a change stream.
+func (b *MongoBroker) applyEventTTL(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+
+	defer cancel()
+
+	command := bson.D{
+		{Key: "collMod", Value: b.collection.Name()},
+		{Key: "index", Value: bson.D{
+			{Key: "name", Value: eventTTLIndex},
+			{Key: "expireAfterSeconds", Value: int64(b.eventTTL.Seconds())},
+		}},
+	}
+
+	err := b.collection.Database().RunCommand(ctx, command).Err()
+
+	var commandError mongo.CommandError
+	if errors.As(err, &commandError) && (commandError.Code == codeNamespaceNotFound || commandError.Code == codeIndexNotFound) {
+		return errors.Wrapf(err, "index %s is missing, apply the database migrations", eventTTLIndex)
+	}
+
+	return errors.Wrap(err, "applying broker event ttl")
+}
+
+func (b *MongoBroker) Publish(ctx context.Context, event *domain.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "marshaling broker event")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	document := eventDocument{
+		RoomID:    event.RoomID,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+
+	if _, err = b.collection.InsertOne(ctx, document); err != nil {
+		return errors.Wrap(err, "inserting broker event")
+	}
+
+	return nil
+}
+
+// Subscribe tails the event collection until ctx is done. A broken change
+// stream is reopened with exponential backoff and resumed after the last
+// delivered event. The event ttl is applied first, since Subscribe runs after
+// the migrations that create the event index.
+func (b *MongoBroker) Subscribe(ctx context.Context, handler Handler) error {
+	logger := zerolog.Ctx(ctx)
+
+	if err := b.applyEventTTL(ctx); err != nil {
+		return err
+	}
+
+	var resumeToken bson.Raw
+
+	retry := mongoRetryMin
+
+	for {
+		delivered, err := b.watch(ctx, handler, &resumeToken)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if delivered {
+			retry = mongoRetryMin
+		}
+
+		logger.Error().Err(err).Dur("retry_in", retry).Msg("watch broker events")
+
+		select {
+		case <-time.After(retry):
+		case <-ctx.Done():
+			return nil
+		}
+
+		retry *= 2
+		if retry > mongoRetryMax {
+			retry = mongoRetryMax
+		}
+	}
+}
+
+func (b *MongoBroker) watch(ctx context.Context, handler Handler, resumeToken *bson.Raw) (bool, error) {
+	logger := zerolog.Ctx(ctx)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "operationType", Value: "insert"}}}},
+	}
+
+	opts := options.ChangeStream()
+	if *resumeToken != nil {
+		opts.SetResumeAfter(*resumeToken)
+	}
+
+	stream, err := b.collection.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return false, errors.Wrap(err, "opening change stream")
+	}
+
+	defer stream.Close(context.Background())
+
+	delivered := false
+
+	for stream.Next(ctx) {
+		*resumeToken = stream.ResumeToken()
+		delivered = true
+
+		var change changeDocument
+		if err = stream.Decode(&change); err != nil {
+			logger.Error().Err(err).Msg("decode broker change")
+			continue
+		}
+
+		var event domain.Event
+		if err = json.Unmarshal(change.FullDocument.Payload, &event); err != nil {
+			logger.Error().Err(err).Msg("decode broker event")
+			continue
+		}
+
+		if err = handler(ctx, &event); err != nil {
+			logger.Error().Err(err).Str("room_id", event.RoomID).Msg("handle broker event")
+		}
+	}
+
+	return delivered, errors.Wrap(stream.Err(), "reading change stream")
+}
@@ -0,0 +1,121 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDatabaseURIEnv names a Mongo replica set to run the change stream of the
+// Mongo broker against. The test is skipped without it.
+const testDatabaseURIEnv = "TEST_DB_URI"
+
+func TestMongoBroker(t *testing.T) {
+	uri := os.Getenv(testDatabaseURIEnv)
+	if uri == "" {
+		t.Skipf("%s is not set", testDatabaseURIEnv)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connecting to mongo: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = client.Disconnect(context.Background())
+	})
+
+	db := client.Database("gochat_test")
+	collection := fmt.Sprintf("events_%d", time.Now().UnixNano())
+
+	t.Cleanup(func() {
+		_ = db.Collection(collection).Drop(context.Background())
+	})
+
+	// the index is created by the create_expiry_indexes migration in the
+	// service, with a ttl the broker has to replace
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: options.Index().SetName(eventTTLIndex).SetExpireAfterSeconds(3600),
+	}
+
+	if _, err = db.Collection(collection).Indexes().CreateOne(ctx, index); err != nil {
+		t.Fatalf("creating event index: %v", err)
+	}
+
+	b, err := NewBroker(ctx, &Deps{Driver: DriverMongo, Database: db, Collection: collection, EventTTL: 5 * time.Minute})
+	if errors.Is(err, ErrNoReplicaSet) {
+		t.Skip("change streams need a replica set")
+	}
+	if err != nil {
+		t.Fatalf("creating broker: %v", err)
+	}
+
+	events := subscribe(t, b)
+
+	// the change stream opens asynchronously, so events are published until
+	// the first one arrives
+	deadline := time.Now().Add(10 * time.Second)
+
+	for {
+		if err = b.Publish(ctx, &domain.Event{ID: "event-1", RoomID: "room"}); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+
+		select {
+		case event := <-events:
+			if event.ID != "event-1" || event.RoomID != "room" {
+				t.Fatalf("got event %+v", event)
+			}
+
+			assertEventTTL(t, db.Collection(collection), 300)
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("no event received")
+		}
+	}
+}
+
+func assertEventTTL(t *testing.T, collection *mongo.Collection, want int64) {
+	t.Helper()
+
+	cursor, err := collection.Indexes().List(context.Background())
+	if err != nil {
+		t.Fatalf("listing indexes: %v", err)
+	}
+
+	var indexes []struct {
+		Name               string `bson:"name"`
+		ExpireAfterSeconds int64  `bson:"expireAfterSeconds"`
+	}
+
+	if err = cursor.All(context.Background(), &indexes); err != nil {
+		t.Fatalf("decoding indexes: %v", err)
+	}
+
+	for _, index := range indexes {
+		if index.Name == eventTTLIndex {
+			if index.ExpireAfterSeconds != want {
+				t.Fatalf("got ttl %d, want %d", index.ExpireAfterSeconds, want)
+			}
+			return
+		}
+	}
+
+	t.Fatalf("index %s is missing", eventTTLIndex)
+}
@@ -2,13 +2,14 @@ package config
 
 import (
 	"time"
-
-	"github.com/kelseyhightower/envconfig"
 )
 
+// Config is populated by Load. Every setting has an env var in its envconfig
+// tag; settings tagged secret are redacted when the configuration is printed
+// and settings tagged reload can be changed without a restart.
 type Config struct {
 	Log struct {
-		LogLevel string `envconfig:"LOG_LEVEL" default:"debug"`
+		LogLevel string `envconfig:"LOG_LEVEL" default:"debug" reload:"true"`
 		LogFile  string `envconfig:"LOG_FILE"`
 		LogSize  int    `envconfig:"LOG_SIZE" default:"10"`
 		LogAge   int    `envconfig:"LOG_AGE" default:"28"`
@@ -19,29 +20,146 @@ type Config struct {
 		Port         string        `envconfig:"HTTP_PORT" default:"8000"`
 		WriteTimeout time.Duration `envconfig:"HTTP_WRITE_TIMEOUT" default:"0"`
 		ReadTimeout  time.Duration `envconfig:"HTTP_READ_TIMEOUT" default:"0"`
+
+		RequireIfMatch     bool     `envconfig:"HTTP_REQUIRE_IF_MATCH" default:"false" reload:"true"`
+		CORSAllowedOrigins []string `envconfig:"HTTP_CORS_ALLOWED_ORIGINS" reload:"true"`
+
+		// TrustedProxies are the CIDR ranges of the reverse proxies allowed
+		// to set X-Forwarded-For. When empty, forwarding headers are ignored.
+		TrustedProxies []string `envconfig:"HTTP_TRUSTED_PROXIES"`
 	}
 
 	Database struct {
-		Host     string `envconfig:"DB_HOST" default:"0.0.0.0"`
-		Port     string `envconfig:"DB_PORT" default:"57017"`
-		Username string `envconfig:"DB_USERNAME"`
-		Password string `envconfig:"DB_PASSWORD"`
-		Auth     string `envconfig:"DB_AUTH"`
-		Name     string `envconfig:"DB_NAME" default:"gochat-db-1"`
+		// URI is a full connection string, e.g. for mongodb+srv deployments.
+		// When set, the connection settings below except the name are ignored.
+		URI           string   `envconfig:"DB_URI" secret:"true"`
+		Host          string   `envconfig:"DB_HOST" default:"0.0.0.0"`
+		Port          string   `envconfig:"DB_PORT" default:"57017"`
+		Hosts         []string `envconfig:"DB_HOSTS"`
+		ReplicaSet    string   `envconfig:"DB_REPLICA_SET"`
+		Username      string   `envconfig:"DB_USERNAME"`
+		Password      string   `envconfig:"DB_PASSWORD" secret:"true"`
+		Auth          string   `envconfig:"DB_AUTH"`
+		AuthMechanism string   `envconfig:"DB_AUTH_MECHANISM"`
+		Name          string   `envconfig:"DB_NAME" default:"gochat-db-1"`
+
+		TLS            bool   `envconfig:"DB_TLS" default:"false"`
+		TLSCAFile      string `envconfig:"DB_TLS_CA_FILE"`
+		TLSCertKeyFile string `envconfig:"DB_TLS_CERT_KEY_FILE"`
+		TLSInsecure    bool   `envconfig:"DB_TLS_INSECURE" default:"false"`
+
+		MinPoolSize            int           `envconfig:"DB_MIN_POOL_SIZE" default:"0"`
+		MaxPoolSize            int           `envconfig:"DB_MAX_POOL_SIZE" default:"100"`
+		MaxConnIdleTime        time.Duration `envconfig:"DB_MAX_CONN_IDLE_TIME" default:"0"`
+		ConnectTimeout         time.Duration `envconfig:"DB_CONNECT_TIMEOUT" default:"10s"`
+		ServerSelectionTimeout time.Duration `envconfig:"DB_SERVER_SELECTION_TIMEOUT" default:"30s"`
+		ConnectAttempts        int           `envconfig:"DB_CONNECT_ATTEMPTS" default:"5"`
+		ConnectBackoff         time.Duration `envconfig:"DB_CONNECT_BACKOFF" default:"1s"`
+
+		ReadPreference string `envconfig:"DB_READ_PREFERENCE" default:"primary"`
+		ReadConcern    string `envconfig:"DB_READ_CONCERN"`
+		WriteConcern   string `envconfig:"DB_WRITE_CONCERN"`
+		RetryWrites    bool   `envconfig:"DB_RETRY_WRITES" default:"true"`
+
+		MigrateOnStart       bool          `envconfig:"DB_MIGRATE_ON_START" default:"true"`
+		MigrationLockTTL     time.Duration `envconfig:"DB_MIGRATION_LOCK_TTL" default:"10m"`
+		MigrationLockTimeout time.Duration `envconfig:"DB_MIGRATION_LOCK_TIMEOUT" default:"1m"`
 	}
 
-	Application struct {
-		Name    string `envconfig:"APP_NAME" default:"gochat"`
-		Version string `envconfig:"APP_VERSION" default:"v0.0.1"`
+	Metrics struct {
+		Enabled bool   `envconfig:"METRICS_ENABLED" default:"true"`
+		Path    string `envconfig:"METRICS_PATH" default:"/metrics"`
+		// Addr serves the metrics on a separate admin listener when set,
+		// instead of on the api listener.
+		Addr string `envconfig:"METRICS_ADDR"`
 	}
-}
 
-func GetConfig() *Config {
-	var cfg Config
+	Tracing struct {
+		// Exporter is one of otlp, stdout or none.
+		Exporter     string  `envconfig:"TRACING_EXPORTER" default:"none"`
+		OTLPEndpoint string  `envconfig:"TRACING_OTLP_ENDPOINT" default:"localhost:4318"`
+		OTLPInsecure bool    `envconfig:"TRACING_OTLP_INSECURE" default:"false"`
+		SampleRatio  float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+	}
+
+	Health struct {
+		CheckTimeout time.Duration `envconfig:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+		DrainDelay   time.Duration `envconfig:"HEALTH_DRAIN_DELAY" default:"0s"`
+	}
+
+	WebSocket struct {
+		AllowedOrigins []string      `envconfig:"WS_ALLOWED_ORIGINS" reload:"true"`
+		SendBuffer     int           `envconfig:"WS_SEND_BUFFER" default:"64"`
+		PingInterval   time.Duration `envconfig:"WS_PING_INTERVAL" default:"30s"`
+		PongWait       time.Duration `envconfig:"WS_PONG_WAIT" default:"60s"`
+		WriteWait      time.Duration `envconfig:"WS_WRITE_WAIT" default:"10s"`
+		MaxMessageSize int64         `envconfig:"WS_MAX_MESSAGE_SIZE" default:"65536"`
+	}
+
+	SSE struct {
+		Buffer            int           `envconfig:"SSE_BUFFER" default:"64"`
+		KeepAliveInterval time.Duration `envconfig:"SSE_KEEP_ALIVE_INTERVAL" default:"15s"`
+		WriteWait         time.Duration `envconfig:"SSE_WRITE_WAIT" default:"10s"`
+		ReplayLimit       int           `envconfig:"SSE_REPLAY_LIMIT" default:"1000"`
+	}
 
-	if err := envconfig.Process("", &cfg); err != nil {
-		return nil
+	Broker struct {
+		Driver   string        `envconfig:"BROKER_DRIVER" default:"memory"`
+		Buffer   int           `envconfig:"BROKER_BUFFER" default:"256"`
+		EventTTL time.Duration `envconfig:"BROKER_EVENT_TTL" default:"1h"`
+	}
+
+	RateLimit struct {
+		Enabled      bool          `envconfig:"RATE_LIMIT_ENABLED" default:"true" reload:"true"`
+		Driver       string        `envconfig:"RATE_LIMIT_DRIVER" default:"memory"`
+		StrictLimit  int           `envconfig:"RATE_LIMIT_STRICT_LIMIT" default:"10" reload:"true"`
+		StrictPeriod time.Duration `envconfig:"RATE_LIMIT_STRICT_PERIOD" default:"1m" reload:"true"`
+		ReadLimit    int           `envconfig:"RATE_LIMIT_READ_LIMIT" default:"300" reload:"true"`
+		ReadPeriod   time.Duration `envconfig:"RATE_LIMIT_READ_PERIOD" default:"1m" reload:"true"`
+		WriteLimit   int           `envconfig:"RATE_LIMIT_WRITE_LIMIT" default:"60" reload:"true"`
+		WritePeriod  time.Duration `envconfig:"RATE_LIMIT_WRITE_PERIOD" default:"1m" reload:"true"`
+	}
+
+	Security struct {
+		PasswordHashCost int `envconfig:"PASSWORD_HASH_COST" default:"12"`
+	}
+
+	Users struct {
+		// Zero in either of DeletedRetention and PurgeInterval disables purging.
+		DeletedRetention time.Duration `envconfig:"USER_DELETED_RETENTION" default:"720h"`
+		PurgeInterval    time.Duration `envconfig:"USER_PURGE_INTERVAL" default:"1h"`
+
+		VerificationTokenTTL time.Duration `envconfig:"USER_VERIFICATION_TOKEN_TTL" default:"24h"`
+		VerifyURL            string        `envconfig:"USER_VERIFY_URL" default:"http://localhost:8000/api/v1/verify"`
+
+		PasswordResetTokenTTL time.Duration `envconfig:"USER_PASSWORD_RESET_TOKEN_TTL" default:"1h"`
+		PasswordResetURL      string        `envconfig:"USER_PASSWORD_RESET_URL" default:"http://localhost:8000/password/reset"`
+	}
+
+	Mail struct {
+		Driver       string `envconfig:"MAIL_DRIVER" default:"log"`
+		From         string `envconfig:"MAIL_FROM" default:"gochat <no-reply@gochat.local>"`
+		SMTPHost     string `envconfig:"MAIL_SMTP_HOST"`
+		SMTPPort     string `envconfig:"MAIL_SMTP_PORT" default:"587"`
+		SMTPUsername string `envconfig:"MAIL_SMTP_USERNAME"`
+		SMTPPassword string `envconfig:"MAIL_SMTP_PASSWORD" secret:"true"`
+		LogFile      string `envconfig:"MAIL_LOG_FILE"`
+	}
+
+	Auth struct {
+		SigningKey      string        `envconfig:"AUTH_SIGNING_KEY" secret:"true"`
+		Issuer          string        `envconfig:"AUTH_ISSUER" default:"gochat"`
+		AccessTokenTTL  time.Duration `envconfig:"AUTH_ACCESS_TOKEN_TTL" default:"15m"`
+		RefreshTokenTTL time.Duration `envconfig:"AUTH_REFRESH_TOKEN_TTL" default:"720h"`
+	}
+
+	Application struct {
+		Name    string `envconfig:"APP_NAME" default:"gochat"`
+		Version string `envconfig:"APP_VERSION" default:"v0.0.1"`
 	}
 
-	return &cfg
+	// sources maps the file key of every setting to the layer it came from.
+	sources map[string]string
+	// args are the command line arguments the configuration was loaded from.
+	args []string
 }
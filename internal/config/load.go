@@ -0,0 +1,398 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Sources of a configuration value, in increasing order of precedence.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+const (
+	// FileFlag and FileEnv name the yaml or toml file that is layered between
+	// the defaults and the environment.
+	FileFlag = "config"
+	FileEnv  = "CONFIG_FILE"
+
+	redacted = "******"
+)
+
+// Setting is one effective configuration value and where it came from.
+type Setting struct {
+	Key    string
+	Env    string
+	Flag   string
+	Value  string
+	Source string
+}
+
+// field is a settable leaf of Config. Its file key is made of the snake
+// cased section and field names, e.g. http_server.port, and its flag is the
+// file key with dashes, e.g. -http-server.port. The env var and the default
+// come from the envconfig and default struct tags.
+type field struct {
+	key          string
+	env          string
+	flag         string
+	defaultValue string
+	hasDefault   bool
+	secret       bool
+	reload       bool
+	value        reflect.Value
+}
+
+// Load builds the configuration from the defaults, the config file, the
+// environment and the command line flags, each layer overriding the previous
+// one, and validates the result. It returns the arguments left after the
+// flags. An invalid configuration is returned together with its
+// *ValidationError, so that it can still be inspected; it must not be run.
+func Load(args []string) (*Config, []string, error) {
+	cfg := &Config{sources: make(map[string]string), args: args}
+	fields := cfg.fields()
+
+	flagSet := flag.NewFlagSet("app", flag.ContinueOnError)
+	configFile := flagSet.String(FileFlag, os.Getenv(FileEnv), "path to a yaml or toml config file")
+
+	byFlag := make(map[string]*field, len(fields))
+	for _, f := range fields {
+		byFlag[f.flag] = f
+		flagSet.String(f.flag, f.defaultValue, fmt.Sprintf("%s (env %s)", f.key, f.env))
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return nil, nil, errors.Wrap(err, "parsing flags")
+	}
+
+	var problems []string
+
+	set := func(f *field, raw, source string) {
+		if err := setValue(f.value, raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s value %q: %s", f.key, source, raw, err))
+			return
+		}
+
+		cfg.sources[f.key] = source
+	}
+
+	for _, f := range fields {
+		cfg.sources[f.key] = SourceDefault
+
+		if f.hasDefault {
+			set(f, f.defaultValue, SourceDefault)
+		}
+	}
+
+	if *configFile != "" {
+		values, err := readFile(*configFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		byKey := make(map[string]*field, len(fields))
+		for _, f := range fields {
+			byKey[f.key] = f
+		}
+
+		for _, key := range sortedKeys(values) {
+			f, ok := byKey[key]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: unknown key in %s", key, *configFile))
+				continue
+			}
+
+			set(f, fileValue(f.value, values[key]), SourceFile)
+		}
+	}
+
+	for _, f := range fields {
+		if raw, ok := os.LookupEnv(f.env); ok {
+			set(f, raw, SourceEnv)
+		}
+	}
+
+	flagSet.Visit(func(fl *flag.Flag) {
+		if f, ok := byFlag[fl.Name]; ok {
+			set(f, fl.Value.String(), SourceFlag)
+		}
+	})
+
+	if len(problems) == 0 {
+		problems = cfg.validate()
+	}
+
+	if len(problems) > 0 {
+		return cfg, flagSet.Args(), &ValidationError{Problems: problems}
+	}
+
+	return cfg, flagSet.Args(), nil
+}
+
+// Settings lists every configuration value with the source it came from.
+// Secrets are redacted.
+func (cfg *Config) Settings() []Setting {
+	fields := cfg.fields()
+	settings := make([]Setting, 0, len(fields))
+
+	for _, f := range fields {
+		value := formatValue(f.value)
+		if f.secret && value != "" {
+			value = redacted
+		}
+
+		settings = append(settings, Setting{
+			Key:    f.key,
+			Env:    f.env,
+			Flag:   "-" + f.flag,
+			Value:  value,
+			Source: cfg.sources[f.key],
+		})
+	}
+
+	return settings
+}
+
+// Reload loads the configuration again from the arguments cfg was loaded
+// from, picking up changes of the config file and the environment.
+func (cfg *Config) Reload() (*Config, error) {
+	next, _, err := Load(cfg.args)
+	if err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// Diff returns the keys of the settings that differ in next, split into the
+// ones that can be applied while running and the ones that need a restart.
+func (cfg *Config) Diff(next *Config) (reloadable, restart []string) {
+	nextFields := next.fields()
+
+	for i, f := range cfg.fields() {
+		if reflect.DeepEqual(f.value.Interface(), nextFields[i].value.Interface()) {
+			continue
+		}
+
+		if f.reload {
+			reloadable = append(reloadable, f.key)
+		} else {
+			restart = append(restart, f.key)
+		}
+	}
+
+	return reloadable, restart
+}
+
+func (cfg *Config) fields() []*field {
+	var fields []*field
+
+	root := reflect.ValueOf(cfg).Elem()
+
+	for i := 0; i < root.NumField(); i++ {
+		section := root.Type().Field(i)
+		if !section.IsExported() || section.Type.Kind() != reflect.Struct {
+			continue
+		}
+
+		for j := 0; j < section.Type.NumField(); j++ {
+			leaf := section.Type.Field(j)
+
+			key := snakeCase(section.Name) + "." + snakeCase(leaf.Name)
+			defaultValue, hasDefault := leaf.Tag.Lookup("default")
+
+			fields = append(fields, &field{
+				key:          key,
+				env:          leaf.Tag.Get("envconfig"),
+				flag:         strings.ReplaceAll(key, "_", "-"),
+				defaultValue: defaultValue,
+				hasDefault:   hasDefault,
+				secret:       leaf.Tag.Get("secret") == "true",
+				reload:       leaf.Tag.Get("reload") == "true",
+				value:        root.Field(i).Field(j),
+			})
+		}
+	}
+
+	return fields
+}
+
+// readFile decodes a yaml or toml file, chosen by its extension, into values
+// keyed like the fields.
+func readFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading config file")
+	}
+
+	var document map[string]interface{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		err = toml.Unmarshal(data, &document)
+	default:
+		return nil, errors.Errorf("config file %s must have a .yaml, .yml or .toml extension", path)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding config file %s", path)
+	}
+
+	values := make(map[string]interface{})
+
+	for sectionName, section := range document {
+		entries, ok := section.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("config file %s: %s must be a table of settings", path, sectionName)
+		}
+
+		for name, value := range entries {
+			values[sectionName+"."+name] = value
+		}
+	}
+
+	return values, nil
+}
+
+// fileValue formats a decoded file value for the kind of the field it sets.
+// yaml and toml decode numbers such as 1e6 or 2.0 as floats, which are
+// written out in full for integer and float fields so that they parse.
+func fileValue(target reflect.Value, value interface{}) string {
+	if list, ok := value.([]interface{}); ok {
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			items = append(items, fmt.Sprint(item))
+		}
+
+		return strings.Join(items, ",")
+	}
+
+	number, ok := value.(float64)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+
+	switch target.Kind() {
+	case reflect.Int, reflect.Int64:
+		if target.Type() != reflect.TypeOf(time.Duration(0)) && number == math.Trunc(number) && math.Abs(number) < math.MaxInt64 {
+			return strconv.FormatInt(int64(number), 10)
+		}
+	case reflect.Float64:
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(value)
+}
+
+func setValue(value reflect.Value, raw string) error {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int64:
+		if value.Type() == reflect.TypeOf(time.Duration(0)) {
+			parsed, err := time.ParseDuration(raw)
+			if err != nil {
+				return errors.New("must be a duration such as 30s or 1h")
+			}
+
+			value.SetInt(int64(parsed))
+			return nil
+		}
+
+		parsed, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+
+		value.SetInt(parsed)
+	case reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+
+		value.SetFloat(parsed)
+	case reflect.Slice:
+		var items []string
+
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		value.Set(reflect.ValueOf(items))
+	default:
+		return errors.Errorf("unsupported type %s", value.Type())
+	}
+
+	return nil
+}
+
+func formatValue(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Slice:
+		return strings.Join(value.Interface().([]string), ",")
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
+
+// snakeCase converts a Go identifier to snake case, keeping acronyms
+// together, e.g. HTTPServer to http_server and OTLPEndpoint to otlp_endpoint.
+func snakeCase(name string) string {
+	runes := []rune(name)
+
+	var builder strings.Builder
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			previousLower := unicode.IsLower(runes[i-1])
+			acronymEnd := unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if previousLower || acronymEnd {
+				builder.WriteByte('_')
+			}
+		}
+
+		builder.WriteRune(unicode.ToLower(r))
+	}
+
+	return builder.String()
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
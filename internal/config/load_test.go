@@ -0,0 +1,276 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testSigningKey = "0123456789abcdef0123456789abcdef"
+
+// testEnv lists the variables the cases set, which are cleared first so that
+// the environment of the test run can not leak into them.
+var testEnv = []string{FileEnv, "LOG_LEVEL", "LOG_SIZE", "HTTP_PORT", "HTTP_CORS_ALLOWED_ORIGINS", "USER_PURGE_INTERVAL"}
+
+type wantSetting struct {
+	value  string
+	source string
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		fileName string
+		env      map[string]string
+		args     []string
+		want     map[string]wantSetting
+		wantArgs []string
+	}{
+		{
+			name: "defaults",
+			want: map[string]wantSetting{
+				"log.log_level":        {value: "debug", source: SourceDefault},
+				"http_server.port":     {value: "8000", source: SourceDefault},
+				"log.log_file":         {value: "", source: SourceDefault},
+				"users.purge_interval": {value: "1h0m0s", source: SourceDefault},
+			},
+		},
+		{
+			name:     "yaml file overrides defaults",
+			fileName: "config.yaml",
+			file:     "log:\n  log_level: info\n  log_size: 20\nhttp_server:\n  cors_allowed_origins: [https://a.example, https://b.example]\nusers:\n  purge_interval: 2h\n",
+			want: map[string]wantSetting{
+				"log.log_level":                    {value: "info", source: SourceFile},
+				"log.log_size":                     {value: "20", source: SourceFile},
+				"http_server.cors_allowed_origins": {value: "https://a.example,https://b.example", source: SourceFile},
+				"users.purge_interval":             {value: "2h0m0s", source: SourceFile},
+				"http_server.port":                 {value: "8000", source: SourceDefault},
+			},
+		},
+		{
+			name:     "toml file overrides defaults",
+			fileName: "config.toml",
+			file:     "[http_server]\nport = 9000\n",
+			want: map[string]wantSetting{
+				"http_server.port": {value: "9000", source: SourceFile},
+			},
+		},
+		{
+			name:     "yaml numbers in exponent form set integers",
+			fileName: "config.yaml",
+			file:     "log:\n  log_size: 1e2\ntracing:\n  sample_ratio: 5e-1\n",
+			want: map[string]wantSetting{
+				"log.log_size":         {value: "100", source: SourceFile},
+				"tracing.sample_ratio": {value: "0.5", source: SourceFile},
+			},
+		},
+		{
+			name:     "toml float sets an integer",
+			fileName: "config.toml",
+			file:     "[log]\nlog_size = 20.0\n",
+			want: map[string]wantSetting{
+				"log.log_size": {value: "20", source: SourceFile},
+			},
+		},
+		{
+			name:     "env overrides file",
+			fileName: "config.yaml",
+			file:     "log:\n  log_level: info\n  log_size: 20\n",
+			env:      map[string]string{"LOG_LEVEL": "warn"},
+			want: map[string]wantSetting{
+				"log.log_level": {value: "warn", source: SourceEnv},
+				"log.log_size":  {value: "20", source: SourceFile},
+			},
+		},
+		{
+			name:     "flags override env",
+			fileName: "config.yaml",
+			file:     "log:\n  log_level: info\n",
+			env:      map[string]string{"LOG_LEVEL": "warn", "HTTP_PORT": "9000"},
+			args:     []string{"-log.log-level=error"},
+			want: map[string]wantSetting{
+				"log.log_level":    {value: "error", source: SourceFlag},
+				"http_server.port": {value: "9000", source: SourceEnv},
+			},
+		},
+		{
+			name: "flag equal to the default still counts as set",
+			env:  map[string]string{"LOG_LEVEL": "warn"},
+			args: []string{"-log.log-level", "debug"},
+			want: map[string]wantSetting{
+				"log.log_level": {value: "debug", source: SourceFlag},
+			},
+		},
+		{
+			name: "empty env value is still set",
+			env:  map[string]string{"HTTP_CORS_ALLOWED_ORIGINS": ""},
+			want: map[string]wantSetting{
+				"http_server.cors_allowed_origins": {value: "", source: SourceEnv},
+			},
+		},
+		{
+			name:     "arguments after the flags are returned",
+			args:     []string{"-http-server.port=9001", "migrate", "up"},
+			want:     map[string]wantSetting{"http_server.port": {value: "9001", source: SourceFlag}},
+			wantArgs: []string{"migrate", "up"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestEnv(t, tt.env)
+
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-" + FileFlag, writeTestFile(t, tt.fileName, tt.file)}, args...)
+			}
+
+			cfg, rest, err := Load(args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			settings := make(map[string]Setting)
+			for _, setting := range cfg.Settings() {
+				settings[setting.Key] = setting
+			}
+
+			for key, want := range tt.want {
+				got, ok := settings[key]
+				if !ok {
+					t.Fatalf("%s: no such setting", key)
+				}
+
+				if got.Value != want.value || got.Source != want.source {
+					t.Errorf("%s: got %q from %s, want %q from %s", key, got.Value, got.Source, want.value, want.source)
+				}
+			}
+
+			if len(rest) != 0 || len(tt.wantArgs) != 0 {
+				if !reflect.DeepEqual(rest, tt.wantArgs) {
+					t.Errorf("got arguments %v, want %v", rest, tt.wantArgs)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFileFromEnv(t *testing.T) {
+	setTestEnv(t, map[string]string{FileEnv: writeTestFile(t, "config.yml", "log:\n  log_level: info\n")})
+
+	cfg, _, err := Load(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Log.LogLevel != "info" || cfg.sources["log.log_level"] != SourceFile {
+		t.Fatalf("got %q from %s, want info from %s", cfg.Log.LogLevel, cfg.sources["log.log_level"], SourceFile)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		fileName       string
+		file           string
+		env            map[string]string
+		args           []string
+		wantValidation bool
+	}{
+		{
+			name:           "unknown file key",
+			fileName:       "config.yaml",
+			file:           "log:\n  level: info\n",
+			wantValidation: true,
+		},
+		{
+			name:           "malformed env value",
+			env:            map[string]string{"LOG_SIZE": "ten"},
+			wantValidation: true,
+		},
+		{
+			name:           "invalid value",
+			args:           []string{"-log.log-level=loud"},
+			wantValidation: true,
+		},
+		{
+			name:           "short signing key",
+			env:            map[string]string{"AUTH_SIGNING_KEY": "too short"},
+			wantValidation: true,
+		},
+		{
+			name:           "fractional number for an integer",
+			fileName:       "config.yaml",
+			file:           "log:\n  log_size: 1.5\n",
+			wantValidation: true,
+		},
+		{
+			name:     "unsupported file extension",
+			fileName: "config.json",
+			file:     "{}",
+		},
+		{
+			name:     "file section is not a table",
+			fileName: "config.yaml",
+			file:     "log: info\n",
+		},
+		{
+			name: "unknown flag",
+			args: []string{"-no-such-flag"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestEnv(t, tt.env)
+
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-" + FileFlag, writeTestFile(t, tt.fileName, tt.file)}, args...)
+			}
+
+			cfg, _, err := Load(args)
+			if err == nil {
+				t.Fatal("got no error")
+			}
+
+			var validationError *ValidationError
+			if errors.As(err, &validationError) != tt.wantValidation {
+				t.Fatalf("got %v, want validation error %v", err, tt.wantValidation)
+			}
+
+			if tt.wantValidation && cfg == nil {
+				t.Fatal("got no config with the validation error")
+			}
+		})
+	}
+}
+
+func setTestEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+
+	for _, key := range testEnv {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	t.Setenv("AUTH_SIGNING_KEY", testSigningKey)
+
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+}
+
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+
+	return path
+}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/broker"
+	"github.com/Meystergod/gochat/pkg/client"
+	"github.com/Meystergod/gochat/pkg/mailer"
+	"github.com/Meystergod/gochat/pkg/ratelimit"
+	"github.com/Meystergod/gochat/pkg/tracing"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minSigningKeyLength is the size of the HS256 digest, the shortest key that
+// does not weaken the signature.
+const minSigningKeyLength = 32
+
+// ValidationError lists every problem found in the configuration, so that
+// they can be fixed at once.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// validator collects the problems of a configuration. Its checks name
+// settings by their file key.
+type validator struct {
+	problems []string
+}
+
+func (cfg *Config) validate() []string {
+	v := &validator{}
+
+	if _, err := zerolog.ParseLevel(cfg.Log.LogLevel); err != nil || cfg.Log.LogLevel == "" {
+		v.addf("log.log_level: %q is not one of trace, debug, info, warn, error, fatal, panic, disabled", cfg.Log.LogLevel)
+	}
+	v.positive("log.log_size", int64(cfg.Log.LogSize))
+	v.positive("log.log_age", int64(cfg.Log.LogAge))
+
+	v.port("http_server.port", cfg.HTTPServer.Port)
+	v.notNegative("http_server.write_timeout", cfg.HTTPServer.WriteTimeout)
+	v.notNegative("http_server.read_timeout", cfg.HTTPServer.ReadTimeout)
+	for _, proxy := range cfg.HTTPServer.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil {
+			v.addf("http_server.trusted_proxies: %q is not a CIDR range", proxy)
+		}
+	}
+
+	v.required("database.name", cfg.Database.Name)
+	v.positive("database.connect_attempts", int64(cfg.Database.ConnectAttempts))
+	v.positiveDuration("database.connect_backoff", cfg.Database.ConnectBackoff)
+	v.positiveDuration("database.server_selection_timeout", cfg.Database.ServerSelectionTimeout)
+
+	if cfg.Database.URI != "" {
+		if !strings.HasPrefix(cfg.Database.URI, "mongodb://") && !strings.HasPrefix(cfg.Database.URI, "mongodb+srv://") {
+			v.addf("database.uri: must start with mongodb:// or mongodb+srv://")
+		}
+	} else {
+		v.validateDatabase(cfg)
+	}
+
+	v.positiveDuration("database.migration_lock_ttl", cfg.Database.MigrationLockTTL)
+	v.positiveDuration("database.migration_lock_timeout", cfg.Database.MigrationLockTimeout)
+
+	if !strings.HasPrefix(cfg.Metrics.Path, "/") {
+		v.addf("metrics.path: %q must start with /", cfg.Metrics.Path)
+	}
+
+	v.positiveDuration("health.check_timeout", cfg.Health.CheckTimeout)
+	v.notNegative("health.drain_delay", cfg.Health.DrainDelay)
+
+	v.positive("web_socket.send_buffer", int64(cfg.WebSocket.SendBuffer))
+	v.positiveDuration("web_socket.ping_interval", cfg.WebSocket.PingInterval)
+	v.positiveDuration("web_socket.write_wait", cfg.WebSocket.WriteWait)
+	v.positive("web_socket.max_message_size", cfg.WebSocket.MaxMessageSize)
+	if cfg.WebSocket.PongWait <= cfg.WebSocket.PingInterval {
+		v.addf("web_socket.pong_wait: %s must be longer than web_socket.ping_interval %s", cfg.WebSocket.PongWait, cfg.WebSocket.PingInterval)
+	}
+
+	v.positive("sse.buffer", int64(cfg.SSE.Buffer))
+	v.positiveDuration("sse.keep_alive_interval", cfg.SSE.KeepAliveInterval)
+	v.positiveDuration("sse.write_wait", cfg.SSE.WriteWait)
+	v.notNegativeInt("sse.replay_limit", int64(cfg.SSE.ReplayLimit))
+
+	v.oneOf("broker.driver", cfg.Broker.Driver, broker.DriverMemory, broker.DriverMongo)
+	v.positive("broker.buffer", int64(cfg.Broker.Buffer))
+	v.positiveDuration("broker.event_ttl", cfg.Broker.EventTTL)
+
+	v.oneOf("rate_limit.driver", cfg.RateLimit.Driver, ratelimit.DriverMemory, ratelimit.DriverMongo)
+	v.positive("rate_limit.strict_limit", int64(cfg.RateLimit.StrictLimit))
+	v.positiveDuration("rate_limit.strict_period", cfg.RateLimit.StrictPeriod)
+	v.positive("rate_limit.read_limit", int64(cfg.RateLimit.ReadLimit))
+	v.positiveDuration("rate_limit.read_period", cfg.RateLimit.ReadPeriod)
+	v.positive("rate_limit.write_limit", int64(cfg.RateLimit.WriteLimit))
+	v.positiveDuration("rate_limit.write_period", cfg.RateLimit.WritePeriod)
+
+	v.oneOf("tracing.exporter", cfg.Tracing.Exporter, tracing.ExporterOTLP, tracing.ExporterStdout, tracing.ExporterNone)
+	if cfg.Tracing.Exporter == tracing.ExporterOTLP {
+		v.required("tracing.otlp_endpoint", cfg.Tracing.OTLPEndpoint)
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		v.addf("tracing.sample_ratio: %v must be between 0 and 1", cfg.Tracing.SampleRatio)
+	}
+
+	if cost := cfg.Security.PasswordHashCost; cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		v.addf("security.password_hash_cost: %d must be between %d and %d", cost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	v.notNegative("users.deleted_retention", cfg.Users.DeletedRetention)
+	v.notNegative("users.purge_interval", cfg.Users.PurgeInterval)
+	v.positiveDuration("users.verification_token_ttl", cfg.Users.VerificationTokenTTL)
+	v.absoluteURL("users.verify_url", cfg.Users.VerifyURL)
+	v.positiveDuration("users.password_reset_token_ttl", cfg.Users.PasswordResetTokenTTL)
+	v.absoluteURL("users.password_reset_url", cfg.Users.PasswordResetURL)
+
+	v.oneOf("mail.driver", cfg.Mail.Driver, mailer.DriverSMTP, mailer.DriverLog)
+	v.required("mail.from", cfg.Mail.From)
+	if cfg.Mail.Driver == mailer.DriverSMTP {
+		v.required("mail.smtp_host", cfg.Mail.SMTPHost)
+		v.port("mail.smtp_port", cfg.Mail.SMTPPort)
+	}
+
+	v.required("auth.signing_key", cfg.Auth.SigningKey)
+	if length := len(cfg.Auth.SigningKey); length > 0 && length < minSigningKeyLength {
+		v.addf("auth.signing_key: must be at least %d bytes long, got %d", minSigningKeyLength, length)
+	}
+	v.positiveDuration("auth.access_token_ttl", cfg.Auth.AccessTokenTTL)
+	v.positiveDuration("auth.refresh_token_ttl", cfg.Auth.RefreshTokenTTL)
+
+	v.required("application.name", cfg.Application.Name)
+
+	return v.problems
+}
+
+// validateDatabase checks the structured connection settings, which are
+// ignored when a connection uri is configured.
+func (v *validator) validateDatabase(cfg *Config) {
+	if len(cfg.Database.Hosts) == 0 {
+		v.port("database.port", cfg.Database.Port)
+		v.required("database.host", cfg.Database.Host)
+	}
+
+	for _, host := range cfg.Database.Hosts {
+		if _, port, err := net.SplitHostPort(host); err != nil {
+			v.addf("database.hosts: %q is not a host:port pair", host)
+		} else {
+			v.port("database.hosts", port)
+		}
+	}
+
+	v.oneOf("database.auth_mechanism", cfg.Database.AuthMechanism, "", "SCRAM-SHA-1", "SCRAM-SHA-256", client.AuthMechanismX509, "PLAIN")
+	if cfg.Database.AuthMechanism == client.AuthMechanismX509 {
+		v.required("database.tls_cert_key_file", cfg.Database.TLSCertKeyFile)
+	}
+
+	v.notNegativeInt("database.min_pool_size", int64(cfg.Database.MinPoolSize))
+	v.positive("database.max_pool_size", int64(cfg.Database.MaxPoolSize))
+	if cfg.Database.MinPoolSize > cfg.Database.MaxPoolSize {
+		v.addf("database.min_pool_size: %d must not exceed database.max_pool_size %d", cfg.Database.MinPoolSize, cfg.Database.MaxPoolSize)
+	}
+
+	v.notNegative("database.max_conn_idle_time", cfg.Database.MaxConnIdleTime)
+	v.positiveDuration("database.connect_timeout", cfg.Database.ConnectTimeout)
+
+	v.oneOf("database.read_preference", cfg.Database.ReadPreference, "primary", "primaryPreferred", "secondary", "secondaryPreferred", "nearest")
+	v.oneOf("database.read_concern", cfg.Database.ReadConcern, "", "local", "available", "majority", "linearizable", "snapshot")
+
+	if w, err := strconv.Atoi(cfg.Database.WriteConcern); err == nil && w < 0 {
+		v.addf("database.write_concern: %d must not be negative", w)
+	}
+}
+
+func (v *validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(key, value string) {
+	if value == "" {
+		v.addf("%s: must be set", key)
+	}
+}
+
+func (v *validator) port(key, value string) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		v.addf("%s: %q is not a port between 1 and 65535", key, value)
+	}
+}
+
+func (v *validator) positive(key string, value int64) {
+	if value <= 0 {
+		v.addf("%s: %d must be greater than 0", key, value)
+	}
+}
+
+func (v *validator) notNegativeInt(key string, value int64) {
+	if value < 0 {
+		v.addf("%s: %d must not be negative", key, value)
+	}
+}
+
+func (v *validator) positiveDuration(key string, value time.Duration) {
+	if value <= 0 {
+		v.addf("%s: %s must be longer than 0", key, value)
+	}
+}
+
+func (v *validator) notNegative(key string, value time.Duration) {
+	if value < 0 {
+		v.addf("%s: %s must not be negative", key, value)
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+
+	v.addf("%s: %q is not one of %s", key, value, strings.Join(allowed, ", "))
+}
+
+func (v *validator) absoluteURL(key, value string) {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		v.addf("%s: %q is not an absolute url", key, value)
+	}
+}
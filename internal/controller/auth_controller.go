@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_auth"
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AuthController struct {
+	authUsecase *usecase_auth.AuthUsecase
+}
+
+func NewAuthController(authUsecase *usecase_auth.AuthUsecase) *AuthController {
+	return &AuthController{authUsecase: authUsecase}
+}
+
+func (authController *AuthController) Login(c echo.Context) error {
+	var payload LoginDTO
+
+	if err := utils.BindAndValidate(c, &payload); err != nil {
+		return apperror.NewValidationError(err)
+	}
+
+	tokens, err := authController.authUsecase.Login(c.Request().Context(), payload.Email, payload.Password)
+	if err != nil {
+		return err
+	}
+
+	return utils.Negotiate(c, http.StatusOK, tokens)
+}
+
+func (authController *AuthController) RefreshToken(c echo.Context) error {
+	var payload RefreshTokenDTO
+
+	if err := utils.BindAndValidate(c, &payload); err != nil {
+		return apperror.NewValidationError(err)
+	}
+
+	tokens, err := authController.authUsecase.Refresh(c.Request().Context(), payload.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	return utils.Negotiate(c, http.StatusOK, tokens)
+}
+
+func (authController *AuthController) Logout(c echo.Context) error {
+	var payload RefreshTokenDTO
+
+	if err := utils.BindAndValidate(c, &payload); err != nil {
+		return apperror.NewValidationError(err)
+	}
+
+	err := authController.authUsecase.Logout(c.Request().Context(), payload.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
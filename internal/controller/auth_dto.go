@@ -0,0 +1,16 @@
+package controller
+
+import "github.com/Meystergod/gochat/internal/utils"
+
+type LoginDTO struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RefreshTokenDTO struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+func (loginDTO *LoginDTO) Normalize() {
+	loginDTO.Email = utils.NormalizeEmail(loginDTO.Email)
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderETag        = "ETag"
+	HeaderIfMatch     = "If-Match"
+	HeaderIfNoneMatch = "If-None-Match"
+
+	weakETagPrefix = "W/"
+)
+
+func formatETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+func setETag(c echo.Context, version int64) {
+	c.Response().Header().Set(HeaderETag, formatETag(version))
+}
+
+// ifMatchVersions returns the versions listed in the If-Match header. Nil is
+// returned for an absent header and for "*", which only requires the object to
+// exist. Weak tags never satisfy If-Match, and a header listing no usable tag
+// can not be satisfied at all.
+func ifMatchVersions(c echo.Context) ([]int64, error) {
+	header := strings.TrimSpace(c.Request().Header.Get(HeaderIfMatch))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	var versions []int64
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, weakETagPrefix) {
+			continue
+		}
+
+		version, ok := parseETag(tag)
+		if ok {
+			versions = append(versions, version)
+		}
+	}
+
+	if len(versions) == 0 {
+		return nil, apperror.NewAppError(apperror.ErrorPreconditionFailed, "If-Match does not list a current entity tag")
+	}
+
+	return versions, nil
+}
+
+// noneMatch reports whether the If-None-Match header of the request does not
+// match the given version, that is whether a full response has to be sent.
+func noneMatch(c echo.Context, version int64) bool {
+	header := strings.TrimSpace(c.Request().Header.Get(HeaderIfNoneMatch))
+	if header == "" {
+		return true
+	}
+	if header == "*" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), weakETagPrefix)
+
+		if tagVersion, ok := parseETag(tag); ok && tagVersion == version {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseETag(tag string) (int64, bool) {
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil || !strings.HasPrefix(tag, `"`) {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return version, true
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newETagContext(header, value string) echo.Context {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	if value != "" {
+		request.Header.Set(header, value)
+	}
+
+	return echo.New().NewContext(request, httptest.NewRecorder())
+}
+
+func TestIfMatchVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    []int64
+		wantErr bool
+	}{
+		{name: "absent", header: ""},
+		{name: "any", header: "*"},
+		{name: "single", header: `"3"`, want: []int64{3}},
+		{name: "list", header: `"3", "5"`, want: []int64{3, 5}},
+		{name: "surrounding whitespace", header: `  "7"  `, want: []int64{7}},
+		{name: "weak tags are skipped", header: `W/"3", "4"`, want: []int64{4}},
+		{name: "only weak tags", header: `W/"3"`, wantErr: true},
+		{name: "unquoted", header: `3`, wantErr: true},
+		{name: "not a version", header: `"abc"`, wantErr: true},
+		{name: "unparsable entries are skipped", header: `"abc", "9"`, want: []int64{9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ifMatchVersions(newETagContext(HeaderIfMatch, tt.header))
+
+			if tt.wantErr {
+				if !errors.Is(err, apperror.ErrorPreconditionFailed) {
+					t.Fatalf("got error %v, want %v", err, apperror.ErrorPreconditionFailed)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoneMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		version int64
+		want    bool
+	}{
+		{name: "absent", header: "", version: 3, want: true},
+		{name: "any", header: "*", version: 3, want: false},
+		{name: "current", header: `"3"`, version: 3, want: false},
+		{name: "stale", header: `"2"`, version: 3, want: true},
+		{name: "list with current", header: `"1", "3"`, version: 3, want: false},
+		{name: "weak current", header: `W/"3"`, version: 3, want: false},
+		{name: "unquoted", header: `3`, version: 3, want: true},
+		{name: "garbage", header: `"abc"`, version: 3, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := noneMatch(newETagContext(HeaderIfNoneMatch, tt.header), tt.version); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatETagRoundTrip(t *testing.T) {
+	for _, version := range []int64{0, 1, 42, 1 << 40} {
+		got, ok := parseETag(formatETag(version))
+		if !ok || got != version {
+			t.Fatalf("parseETag(formatETag(%d)) = %d, %v", version, got, ok)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/realtime"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_message"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_room"
+	"github.com/Meystergod/gochat/pkg/httpserver"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+const headerLastEventID = "Last-Event-ID"
+
+type EventControllerDeps struct {
+	Hub               *realtime.Hub
+	RoomUsecase       *usecase_room.RoomUsecase
+	MessageUsecase    *usecase_message.MessageUsecase
+	Buffer            int
+	KeepAliveInterval time.Duration
+	WriteWait         time.Duration
+	ReplayLimit       int
+}
+
+type EventController struct {
+	hub               *realtime.Hub
+	roomUsecase       *usecase_room.RoomUsecase
+	messageUsecase    *usecase_message.MessageUsecase
+	buffer            int
+	keepAliveInterval time.Duration
+	writeWait         time.Duration
+	replayLimit       int
+}
+
+func NewEventController(deps *EventControllerDeps) *EventController {
+	return &EventController{
+		hub:               deps.Hub,
+		roomUsecase:       deps.RoomUsecase,
+		messageUsecase:    deps.MessageUsecase,
+		buffer:            deps.Buffer,
+		keepAliveInterval: deps.KeepAliveInterval,
+		writeWait:         deps.WriteWait,
+		replayLimit:       deps.ReplayLimit,
+	}
+}
+
+// StreamRoomEvents serves room events as server-sent events. When the client
+// resumes with Last-Event-ID, messages persisted after that id are replayed
+// from history before live events are streamed. A replay cut short by the
+// replay limit or by a failed read ends with a stream.reset event.
+func (eventController *EventController) StreamRoomEvents(c echo.Context) error {
+	principal, err := principalFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	roomID := c.Param("id")
+
+	ctx := c.Request().Context()
+	logger := zerolog.Ctx(ctx)
+
+	if _, err = eventController.roomUsecase.GetRoom(ctx, principal, roomID); err != nil {
+		return err
+	}
+
+	subscription, err := eventController.hub.Subscribe(roomID, eventController.buffer)
+	if err != nil {
+		if errors.Is(err, realtime.ErrDraining) {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "server is shutting down")
+		}
+		return err
+	}
+
+	defer eventController.hub.Unsubscribe(subscription)
+
+	lastEventID := c.Request().Header.Get(headerLastEventID)
+	if lastEventID == "" {
+		lastEventID = c.QueryParam("last_event_id")
+	}
+
+	// the first replay page is read before the stream starts, so that a
+	// malformed id or a failing read is answered with an error response; an
+	// empty stream would make the client reconnect with the same id forever
+	var page *domain.MessagePage
+	if lastEventID != "" {
+		page, err = eventController.messageUsecase.GetHistory(ctx, principal, replayCursor(roomID, lastEventID))
+		if err != nil {
+			return err
+		}
+	}
+
+	writer := httpserver.NewSSEWriter(c, eventController.writeWait)
+
+	if page != nil {
+		lastEventID, err = eventController.replay(ctx, writer, principal, roomID, lastEventID, page)
+		if err != nil {
+			logger.Debug().Err(err).Str("room_id", roomID).Msg("replay room events")
+
+			_ = writeEvent(writer, newResetEvent(roomID))
+			return nil
+		}
+	}
+
+	keepAlive := time.NewTicker(eventController.keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event := <-subscription.Events():
+			// skip live events already delivered by the replay
+			if event.Type == domain.EventMessageCreated && event.ID <= lastEventID {
+				continue
+			}
+
+			if err = writeEvent(writer, event); err != nil {
+				logger.Debug().Err(err).Str("room_id", roomID).Msg("write room event")
+				return nil
+			}
+		case <-keepAlive.C:
+			if err = writer.Comment("keep-alive"); err != nil {
+				return nil
+			}
+		case <-subscription.Done():
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// replay writes the messages persisted after the given id, starting with the
+// already read first page. When more than replayLimit messages are missing the
+// rest is skipped and a reset event is sent in their place.
+func (eventController *EventController) replay(ctx context.Context, writer *httpserver.SSEWriter, principal *domain.Principal, roomID, after string, page *domain.MessagePage) (string, error) {
+	replayed := 0
+
+	for {
+		for i := range page.Messages {
+			if replayed == eventController.replayLimit {
+				return after, writeEvent(writer, newResetEvent(roomID))
+			}
+
+			message := &page.Messages[i]
+
+			eventType := domain.EventMessageCreated
+			if message.IsDeleted() {
+				eventType = domain.EventMessageDeleted
+			}
+
+			if err := writeEvent(writer, domain.NewMessageEvent(eventType, message)); err != nil {
+				return after, err
+			}
+
+			after = message.ID
+			replayed++
+		}
+
+		if !page.HasMore {
+			return after, nil
+		}
+
+		if replayed == eventController.replayLimit {
+			return after, writeEvent(writer, newResetEvent(roomID))
+		}
+
+		var err error
+
+		page, err = eventController.messageUsecase.GetHistory(ctx, principal, replayCursor(roomID, after))
+		if err != nil {
+			return after, err
+		}
+	}
+}
+
+func replayCursor(roomID, after string) *domain.MessageCursor {
+	return &domain.MessageCursor{
+		RoomID: roomID,
+		After:  after,
+		Limit:  usecase_message.MaxPageLimit,
+	}
+}
+
+func newResetEvent(roomID string) *domain.Event {
+	return &domain.Event{
+		Type:      domain.EventStreamReset,
+		RoomID:    roomID,
+		CreatedAt: time.Now(),
+	}
+}
+
+// writeEvent sends the event to the stream. Only message.created events carry
+// an id, so Last-Event-ID always points at a position in message history.
+func writeEvent(writer *httpserver.SSEWriter, event *domain.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	id := ""
+	if event.Type == domain.EventMessageCreated {
+		id = event.ID
+	}
+
+	return writer.Event(id, event.Type, data)
+}
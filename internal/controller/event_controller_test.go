@@ -0,0 +1,308 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/realtime"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_message"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_room"
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// historyStore serves the messages of one private room. Message ids are
+// zero padded so that they sort like object ids, and an id without the m
+// prefix is rejected the way the repository rejects a malformed object id.
+type historyStore struct {
+	messages []domain.Message
+}
+
+func newHistoryStore(count int) *historyStore {
+	store := &historyStore{}
+	for i := 1; i <= count; i++ {
+		store.messages = append(store.messages, domain.Message{ID: fmt.Sprintf("m%04d", i), RoomID: "room", Body: "hello"})
+	}
+
+	return store
+}
+
+func (s *historyStore) GetMessages(_ context.Context, cursor *domain.MessageCursor) (*domain.MessagePage, error) {
+	if !strings.HasPrefix(cursor.After, "m") {
+		return nil, apperror.NewAppError(apperror.ErrorInvalidID, "failed to convert message id to oid")
+	}
+
+	page := &domain.MessagePage{}
+
+	for _, message := range s.messages {
+		if message.ID <= cursor.After {
+			continue
+		}
+
+		if len(page.Messages) == cursor.Limit {
+			page.HasMore = true
+			break
+		}
+
+		page.Messages = append(page.Messages, message)
+	}
+
+	return page, nil
+}
+
+func (s *historyStore) CreateMessage(context.Context, *domain.Message) (string, error) {
+	return "", nil
+}
+
+func (s *historyStore) GetMessage(context.Context, string) (*domain.Message, error) {
+	return nil, apperror.NewAppError(apperror.ErrorMessageNotFound, "message with this id does not exist")
+}
+
+func (s *historyStore) EditMessage(context.Context, string, string, time.Time) error {
+	return nil
+}
+
+func (s *historyStore) DeleteMessage(context.Context, string, time.Time) error {
+	return nil
+}
+
+type singleRoomStore struct {
+	room *domain.Room
+}
+
+func (s *singleRoomStore) GetRoom(context.Context, string) (*domain.Room, error) {
+	return s.room, nil
+}
+
+func (s *singleRoomStore) CreateRoom(context.Context, *domain.Room) (string, error) {
+	return "", nil
+}
+
+func (s *singleRoomStore) GetRooms(context.Context, *domain.RoomFilter) ([]domain.Room, error) {
+	return nil, nil
+}
+
+func (s *singleRoomStore) RenameRoom(context.Context, string, string, string) error {
+	return nil
+}
+
+func (s *singleRoomStore) ArchiveRoom(context.Context, string, time.Time) error {
+	return nil
+}
+
+func (s *singleRoomStore) DeleteRoom(context.Context, string) error {
+	return nil
+}
+
+func (s *singleRoomStore) DeleteRoomMessages(context.Context, string) error {
+	return nil
+}
+
+type noopPublisher struct{}
+
+func (noopPublisher) Publish(context.Context, *domain.Event) error {
+	return nil
+}
+
+func (noopPublisher) RecordMessageSent() {}
+
+func newTestEventController(history *historyStore, replayLimit int) *EventController {
+	rooms := &singleRoomStore{room: &domain.Room{ID: "room", Type: domain.RoomTypePrivate, OwnerID: "owner", Members: []string{"owner"}}}
+	roomUsecase := usecase_room.NewRoomUsecase(&usecase_room.RoomUsecaseDeps{RoomRepository: rooms, MessageRemover: rooms})
+
+	return NewEventController(&EventControllerDeps{
+		Hub:         realtime.NewHub(),
+		RoomUsecase: roomUsecase,
+		MessageUsecase: usecase_message.NewMessageUsecase(&usecase_message.MessageUsecaseDeps{
+			MessageRepository: history,
+			RoomProvider:      roomUsecase,
+			EventPublisher:    noopPublisher{},
+			MetricsRecorder:   noopPublisher{},
+		}),
+		Buffer:            8,
+		KeepAliveInterval: time.Hour,
+		WriteWait:         time.Second,
+		ReplayLimit:       replayLimit,
+	})
+}
+
+// streamEvents runs the stream with an already cancelled request, so that the
+// handler returns as soon as the replay is written. It returns the events as
+// "type id" pairs.
+func streamEvents(t *testing.T, controller *EventController, userID, lastEventID string) ([]string, *httptest.ResponseRecorder, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(utils.ContextWithPrincipal(context.Background(), &domain.Principal{UserID: userID, Role: domain.RoleUser}))
+	cancel()
+
+	request := httptest.NewRequest(http.MethodGet, "/rooms/room/events", nil).WithContext(ctx)
+	if lastEventID != "" {
+		request.Header.Set(headerLastEventID, lastEventID)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	c := echo.New().NewContext(request, recorder)
+	c.SetParamNames("id")
+	c.SetParamValues("room")
+
+	err := controller.StreamRoomEvents(c)
+
+	var events []string
+
+	for _, block := range strings.Split(recorder.Body.String(), "\n\n") {
+		var id, event string
+
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			}
+		}
+
+		if event != "" {
+			events = append(events, strings.TrimSpace(event+" "+id))
+		}
+	}
+
+	return events, recorder, err
+}
+
+func messageEvents(from, to int) []string {
+	var events []string
+	for i := from; i <= to; i++ {
+		events = append(events, fmt.Sprintf("%s m%04d", domain.EventMessageCreated, i))
+	}
+
+	return events
+}
+
+func TestStreamRoomEventsReplay(t *testing.T) {
+	tests := []struct {
+		name        string
+		messages    int
+		replayLimit int
+		lastEventID string
+		want        []string
+	}{
+		{
+			name:        "no last event id streams live events only",
+			messages:    3,
+			replayLimit: 10,
+		},
+		{
+			name:        "messages after the last event id are replayed",
+			messages:    5,
+			replayLimit: 10,
+			lastEventID: "m0002",
+			want:        messageEvents(3, 5),
+		},
+		{
+			name:        "nothing to replay",
+			messages:    5,
+			replayLimit: 10,
+			lastEventID: "m0005",
+		},
+		{
+			name:        "replay over several pages",
+			messages:    usecase_message.MaxPageLimit + 20,
+			replayLimit: 1000,
+			lastEventID: "m0000",
+			want:        messageEvents(1, usecase_message.MaxPageLimit+20),
+		},
+		{
+			name:        "replay cut at the limit ends with a reset",
+			messages:    5,
+			replayLimit: 2,
+			lastEventID: "m0000",
+			want:        append(messageEvents(1, 2), domain.EventStreamReset),
+		},
+		{
+			name:        "limit at a page boundary ends with a reset",
+			messages:    usecase_message.MaxPageLimit + 1,
+			replayLimit: usecase_message.MaxPageLimit,
+			lastEventID: "m0000",
+			want:        append(messageEvents(1, usecase_message.MaxPageLimit), domain.EventStreamReset),
+		},
+		{
+			name:        "limit equal to the missing messages needs no reset",
+			messages:    3,
+			replayLimit: 3,
+			lastEventID: "m0000",
+			want:        messageEvents(1, 3),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := newTestEventController(newHistoryStore(tt.messages), tt.replayLimit)
+
+			events, recorder, err := streamEvents(t, controller, "owner", tt.lastEventID)
+			if err != nil {
+				t.Fatalf("stream: %v", err)
+			}
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("got status %d, want %d", recorder.Code, http.StatusOK)
+			}
+
+			if !reflect.DeepEqual(events, tt.want) {
+				t.Fatalf("got events %v, want %v", events, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamRoomEventsReplaysDeletedMessages(t *testing.T) {
+	history := newHistoryStore(2)
+
+	deletedAt := time.Now()
+	history.messages[0].DeletedAt = &deletedAt
+
+	events, _, err := streamEvents(t, newTestEventController(history, 10), "owner", "m0000")
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+
+	want := []string{domain.EventMessageDeleted, domain.EventMessageCreated + " m0002"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("got events %v, want %v", events, want)
+	}
+}
+
+func TestStreamRoomEventsRejectsBeforeStreaming(t *testing.T) {
+	tests := []struct {
+		name        string
+		userID      string
+		lastEventID string
+		wantErr     error
+	}{
+		{name: "malformed last event id", userID: "owner", lastEventID: "not-an-id", wantErr: apperror.ErrorInvalidID},
+		{name: "caller outside the room", userID: "outsider", lastEventID: "m0000", wantErr: apperror.ErrorForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, recorder, err := streamEvents(t, newTestEventController(newHistoryStore(1), 10), tt.userID, tt.lastEventID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+
+			if recorder.Body.Len() != 0 || recorder.Header().Get(echo.HeaderContentType) != "" {
+				t.Fatal("stream was started before the error")
+			}
+		})
+	}
+}
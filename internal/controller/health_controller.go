@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/health"
+
+	"github.com/labstack/echo/v4"
+)
+
+type HealthController struct {
+	health *health.Health
+}
+
+func NewHealthController(health *health.Health) *HealthController {
+	return &HealthController{health: health}
+}
+
+// Liveness reports that the process is up and serving requests. It does not
+// look at dependencies, so that an outage of Mongo does not get the instance
+// restarted.
+func (healthController *HealthController) Liveness(c echo.Context) error {
+	return utils.Negotiate(c, http.StatusOK, HealthResponseDTO{Status: health.StatusOK})
+}
+
+// Readiness reports whether the instance should receive traffic.
+func (healthController *HealthController) Readiness(c echo.Context) error {
+	return healthController.report(c, false)
+}
+
+// Health is Readiness with the outcome and latency of every dependency check.
+func (healthController *HealthController) Health(c echo.Context) error {
+	return healthController.report(c, true)
+}
+
+func (healthController *HealthController) report(c echo.Context, detailed bool) error {
+	report := healthController.health.Report(c.Request().Context())
+
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+
+	return utils.Negotiate(c, status, NewHealthResponseDTO(report, detailed))
+}
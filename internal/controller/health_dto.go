@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"encoding/xml"
+
+	"github.com/Meystergod/gochat/pkg/health"
+)
+
+type HealthCheckDTO struct {
+	Name      string  `json:"name" xml:"name,attr"`
+	Status    string  `json:"status" xml:"status,attr"`
+	LatencyMS float64 `json:"latency_ms" xml:"latency_ms,attr"`
+	Error     string  `json:"error,omitempty" xml:",chardata"`
+}
+
+type HealthResponseDTO struct {
+	XMLName xml.Name         `json:"-" xml:"health"`
+	Status  string           `json:"status" xml:"status"`
+	Checks  []HealthCheckDTO `json:"checks,omitempty" xml:"checks>check,omitempty"`
+}
+
+func NewHealthResponseDTO(report *health.Report, detailed bool) HealthResponseDTO {
+	response := HealthResponseDTO{Status: report.Status}
+
+	if !detailed {
+		return response
+	}
+
+	response.Checks = make([]HealthCheckDTO, 0, len(report.Checks))
+	for _, check := range report.Checks {
+		response.Checks = append(response.Checks, HealthCheckDTO{
+			Name:      check.Name,
+			Status:    check.Status,
+			LatencyMS: float64(check.Latency.Microseconds()) / 1000,
+			Error:     check.Error,
+		})
+	}
+
+	return response
+}
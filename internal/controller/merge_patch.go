@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+
+	"github.com/labstack/echo/v4"
+)
+
+const MIMEApplicationMergePatchJSON = "application/merge-patch+json"
+
+// bindMergePatch decodes an RFC 7396 merge patch into a struct of pointer
+// fields. Members set to null would remove the value, which is rejected since
+// every patchable field is mandatory, and members unknown to the struct are
+// rejected as well instead of being silently dropped.
+func bindMergePatch(c echo.Context, i interface{}) error {
+	mediaType, _, err := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if err != nil || (mediaType != MIMEApplicationMergePatchJSON && mediaType != echo.MIMEApplicationJSON) {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "content type must be "+MIMEApplicationMergePatchJSON)
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return apperror.NewAppError(apperror.ErrorMalformedPayload, err.Error())
+	}
+
+	var members map[string]json.RawMessage
+	if err = json.Unmarshal(body, &members); err != nil || members == nil {
+		return apperror.NewAppError(apperror.ErrorMalformedPayload, "merge patch must be a json object")
+	}
+
+	known := mergePatchFields(i)
+
+	var details []apperror.FieldError
+
+	for name, value := range members {
+		switch {
+		case !known[name]:
+			details = append(details, apperror.FieldError{
+				Field:   name,
+				Rule:    "unknown",
+				Message: "field can not be patched",
+			})
+		case bytes.Equal(bytes.TrimSpace(value), []byte("null")):
+			details = append(details, apperror.FieldError{
+				Field:   name,
+				Rule:    "required",
+				Message: "field can not be removed",
+			})
+		}
+	}
+
+	if len(details) > 0 {
+		sort.Slice(details, func(a, b int) bool { return details[a].Field < details[b].Field })
+		return apperror.NewFieldValidationError(details)
+	}
+
+	if err = json.Unmarshal(body, i); err != nil {
+		return apperror.NewAppError(apperror.ErrorMalformedPayload, err.Error())
+	}
+
+	return nil
+}
+
+func mergePatchFields(i interface{}) map[string]bool {
+	t := reflect.TypeOf(i).Elem()
+	fields := make(map[string]bool, t.NumField())
+
+	for n := 0; n < t.NumField(); n++ {
+		name := strings.SplitN(t.Field(n).Tag.Get("json"), ",", 2)[0]
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestBindMergePatch(t *testing.T) {
+	name := "alice"
+	email := "alice@example.com"
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        PatchUserDTO
+		wantStatus  int
+		wantKind    error
+		wantFields  []string
+	}{
+		{
+			name:        "empty patch",
+			contentType: MIMEApplicationMergePatchJSON,
+			body:        `{}`,
+		},
+		{
+			name:        "members are set",
+			contentType: MIMEApplicationMergePatchJSON,
+			body:        `{"name": "alice", "email": "alice@example.com"}`,
+			want:        PatchUserDTO{Name: &name, Email: &email},
+		},
+		{
+			name:        "plain json is accepted",
+			contentType: echo.MIMEApplicationJSONCharsetUTF8,
+			body:        `{"name": "alice"}`,
+			want:        PatchUserDTO{Name: &name},
+		},
+		{
+			name:        "null member can not remove a field",
+			contentType: MIMEApplicationMergePatchJSON,
+			body:        `{"name": null}`,
+			wantKind:    apperror.ErrorValidatePayload,
+			wantFields:  []string{"name"},
+		},
+		{
+			name:        "null with whitespace",
+			contentType: MIMEApplicationMergePatchJSON,
+			body:        `{"email":  null }`,
+			wantKind:    apperror.ErrorValidatePayload,
+			wantFields:  []string{"email"},
+		},
+		{
+			name:        "unknown member",
+			contentType: MIMEApplicationMergePatchJSON,
+			body:        `{"role": "admin"}`,
+			wantKind:    apperror.ErrorValidatePayload,
+			wantFields:  []string{"role"},
+		},
+		{
+			name:        "every problem is reported in field order",
+			contentType: MIMEApplicationMergePatchJSON,
+			body:        `{"role": "admin", "name": null, "id": "1"}`,
+			wantKind:    apperror.ErrorValidatePayload,
+			wantFields:  []string{"id", "name", "role"},
+		},
+		{
+			name:        "not an object",
+			contentType: MIMEApplicationMergePatchJSON,
+			body:        `["name"]`,
+			wantKind:    apperror.ErrorMalformedPayload,
+		},
+		{
+			name:        "null document",
+			contentType: MIMEApplicationMergePatchJSON,
+			body:        `null`,
+			wantKind:    apperror.ErrorMalformedPayload,
+		},
+		{
+			name:        "wrong member type",
+			contentType: MIMEApplicationMergePatchJSON,
+			body:        `{"name": 1}`,
+			wantKind:    apperror.ErrorMalformedPayload,
+		},
+		{
+			name:        "unsupported content type",
+			contentType: echo.MIMETextPlain,
+			body:        `{}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tt.body))
+			request.Header.Set(echo.HeaderContentType, tt.contentType)
+			c := echo.New().NewContext(request, httptest.NewRecorder())
+
+			var got PatchUserDTO
+			err := bindMergePatch(c, &got)
+
+			switch {
+			case tt.wantStatus != 0:
+				var httpError *echo.HTTPError
+				if !errors.As(err, &httpError) || httpError.Code != tt.wantStatus {
+					t.Fatalf("got error %v, want status %d", err, tt.wantStatus)
+				}
+			case tt.wantKind != nil:
+				if !errors.Is(err, tt.wantKind) {
+					t.Fatalf("got error %v, want %v", err, tt.wantKind)
+				}
+
+				if tt.wantFields != nil {
+					var appError *apperror.AppError
+					errors.As(err, &appError)
+
+					var fields []string
+					for _, detail := range appError.Details {
+						fields = append(fields, detail.Field)
+					}
+
+					if !reflect.DeepEqual(fields, tt.wantFields) {
+						t.Fatalf("got fields %v, want %v", fields, tt.wantFields)
+					}
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("got %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_message"
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+type MessageController struct {
+	messageUsecase *usecase_message.MessageUsecase
+}
+
+func NewMessageController(messageUsecase *usecase_message.MessageUsecase) *MessageController {
+	return &MessageController{messageUsecase: messageUsecase}
+}
+
+func (messageController *MessageController) SendMessage(c echo.Context) error {
+	principal, err := principalFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	roomID := c.Param("id")
+	if roomID == "" {
+		return apperror.NewAppError(apperror.ErrorGetUrlParams, "could not get room id")
+	}
+
+	var payload SendMessageDTO
+
+	if err = utils.BindAndValidate(c, &payload); err != nil {
+		return apperror.NewValidationError(err)
+	}
+
+	message, err := messageController.messageUsecase.SendMessage(c.Request().Context(), principal, roomID, payload.Body)
+	if err != nil {
+		return err
+	}
+
+	return utils.Negotiate(c, http.StatusCreated, map[string]MessageResponseDTO{"message": NewMessageResponseDTO(message)})
+}
+
+func (messageController *MessageController) GetHistory(c echo.Context) error {
+	principal, err := principalFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	roomID := c.Param("id")
+	if roomID == "" {
+		return apperror.NewAppError(apperror.ErrorGetUrlParams, "could not get room id")
+	}
+
+	cursor := &domain.MessageCursor{
+		RoomID: roomID,
+		Before: c.QueryParam("before"),
+		After:  c.QueryParam("after"),
+	}
+
+	if value := c.QueryParam("limit"); value != "" {
+		cursor.Limit, err = strconv.Atoi(value)
+		if err != nil {
+			return apperror.NewAppError(apperror.ErrorGetUrlParams, "limit must be an integer")
+		}
+	}
+
+	page, err := messageController.messageUsecase.GetHistory(c.Request().Context(), principal, cursor)
+	if err != nil {
+		return err
+	}
+
+	return utils.Negotiate(c, http.StatusOK, NewMessagePageResponseDTO(page))
+}
+
+func (messageController *MessageController) EditMessage(c echo.Context) error {
+	principal, err := principalFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	roomID, messageID := c.Param("id"), c.Param("message_id")
+	if roomID == "" || messageID == "" {
+		return apperror.NewAppError(apperror.ErrorGetUrlParams, "could not get room or message id")
+	}
+
+	var payload EditMessageDTO
+
+	if err = utils.BindAndValidate(c, &payload); err != nil {
+		return apperror.NewValidationError(err)
+	}
+
+	err = messageController.messageUsecase.EditMessage(c.Request().Context(), principal, roomID, messageID, payload.Body)
+	if err != nil {
+		return err
+	}
+
+	return utils.Negotiate(c, http.StatusOK, map[string]string{"id": messageID})
+}
+
+func (messageController *MessageController) DeleteMessage(c echo.Context) error {
+	principal, err := principalFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	roomID, messageID := c.Param("id"), c.Param("message_id")
+	if roomID == "" || messageID == "" {
+		return apperror.NewAppError(apperror.ErrorGetUrlParams, "could not get room or message id")
+	}
+
+	err = messageController.messageUsecase.DeleteMessage(c.Request().Context(), principal, roomID, messageID)
+	if err != nil {
+		return err
+	}
+
+	return utils.Negotiate(c, http.StatusOK, map[string]string{"id": messageID})
+}
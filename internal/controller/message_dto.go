@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/Meystergod/gochat/internal/domain"
+)
+
+type SendMessageDTO struct {
+	Body string `json:"body" validate:"required,max=4000"`
+}
+
+type EditMessageDTO struct {
+	Body string `json:"body" validate:"required,max=4000"`
+}
+
+type MessageResponseDTO struct {
+	ID        string     `json:"id" xml:"id"`
+	RoomID    string     `json:"room_id" xml:"room_id"`
+	AuthorID  string     `json:"author_id" xml:"author_id"`
+	Body      string     `json:"body" xml:"body"`
+	CreatedAt time.Time  `json:"created_at" xml:"created_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty" xml:"edited_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" xml:"deleted_at,omitempty"`
+}
+
+type MessagePageResponseDTO struct {
+	Messages   []MessageResponseDTO `json:"messages" xml:"messages>message"`
+	HasMore    bool                 `json:"has_more" xml:"has_more"`
+	NextBefore string               `json:"next_before,omitempty" xml:"next_before,omitempty"`
+	NextAfter  string               `json:"next_after,omitempty" xml:"next_after,omitempty"`
+}
+
+func NewMessageResponseDTO(message *domain.Message) MessageResponseDTO {
+	return MessageResponseDTO{
+		ID:        message.ID,
+		RoomID:    message.RoomID,
+		AuthorID:  message.AuthorID,
+		Body:      message.Body,
+		CreatedAt: message.CreatedAt,
+		EditedAt:  message.EditedAt,
+		DeletedAt: message.DeletedAt,
+	}
+}
+
+func NewMessagePageResponseDTO(page *domain.MessagePage) MessagePageResponseDTO {
+	messages := make([]MessageResponseDTO, 0, len(page.Messages))
+	for i := range page.Messages {
+		messages = append(messages, NewMessageResponseDTO(&page.Messages[i]))
+	}
+
+	return MessagePageResponseDTO{
+		Messages:   messages,
+		HasMore:    page.HasMore,
+		NextBefore: page.NextBefore,
+		NextAfter:  page.NextAfter,
+	}
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_password"
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+type PasswordController struct {
+	passwordUsecase *usecase_password.PasswordUsecase
+}
+
+func NewPasswordController(passwordUsecase *usecase_password.PasswordUsecase) *PasswordController {
+	return &PasswordController{passwordUsecase: passwordUsecase}
+}
+
+func (passwordController *PasswordController) ForgotPassword(c echo.Context) error {
+	var payload ForgotPasswordDTO
+
+	if err := utils.BindAndValidate(c, &payload); err != nil {
+		return apperror.NewValidationError(err)
+	}
+
+	passwordController.passwordUsecase.ForgotPassword(c.Request().Context(), payload.Email)
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (passwordController *PasswordController) ResetPassword(c echo.Context) error {
+	var payload ResetPasswordDTO
+
+	if err := utils.BindAndValidate(c, &payload); err != nil {
+		return apperror.NewValidationError(err)
+	}
+
+	err := passwordController.passwordUsecase.ResetPassword(c.Request().Context(), payload.Token, payload.Password)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
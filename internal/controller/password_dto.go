@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"github.com/Meystergod/gochat/internal/utils"
+)
+
+type ForgotPasswordDTO struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordDTO struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6,maxbytes=72"`
+}
+
+func (forgotPasswordDTO *ForgotPasswordDTO) Normalize() {
+	forgotPasswordDTO.Email = utils.NormalizeEmail(forgotPasswordDTO.Email)
+}
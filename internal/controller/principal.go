@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+func principalFromRequest(c echo.Context) (*domain.Principal, error) {
+	principal, ok := utils.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return nil, apperror.NewAppError(apperror.ErrorUnauthorized, "caller is not authenticated")
+	}
+
+	return principal, nil
+}
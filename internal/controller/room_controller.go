@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_room"
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+type RoomController struct {
+	roomUsecase *usecase_room.RoomUsecase
+}
+
+func NewRoomController(roomUsecase *usecase_room.RoomUsecase) *RoomController {
+	return &RoomController{roomUsecase: roomUsecase}
+}
+
+func (roomController *RoomController) CreateRoom(c echo.Context) error {
+	principal, err := principalFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	var payload CreateRoomDTO
+
+	if err = utils.BindAndValidate(c, &payload); err != nil {
+		return apperror.NewValidationError(err)
+	}
+
+	createdRoomID, err := roomController.roomUsecase.CreateRoom(c.Request().Context(), principal, payload.ToModel())
+	if err != nil {
+		return err
+	}
+
+	return utils.Negotiate(c, http.StatusCreated, map[string]string{"id": createdRoomID})
+}
+
+func (roomController *RoomController) GetRoom(c echo.Context) error {
+	principal, err := principalFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		return apperror.NewAppError(apperror.ErrorGetUrlParams, "could not get room id")
+	}
+
+	room, err := roomController.roomUsecase.GetRoom(c.Request().Context(), principal, id)
+	if err != nil {
+		return err
+	}
+
+	return utils.Negotiate(c, http.StatusOK, map[string]RoomResponseDTO{"room": NewRoomResponseDTO(room)})
+}
+
+func (roomController *RoomController) GetRooms(c echo.Context) error {
+	principal, err := principalFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	var includeArchived bool
+
+	if value := c.QueryParam("archived"); value != "" {
+		includeArchived, err = strconv.ParseBool(value)
+		if err != nil {
+			return apperror.NewAppError(apperror.ErrorGetUrlParams, "archived must be a boolean")
+		}
+	}
+
+	rooms, err := roomController.roomUsecase.GetRooms(c.Request().Context(), principal, includeArchived)
+	if err != nil {
+		return err
+	}
+
+	return utils.Negotiate(c, http.StatusOK, map[string][]RoomResponseDTO{"rooms": NewRoomResponseDTOs(rooms)})
+}
+
+func (roomController *RoomController) RenameRoom(c echo.Context) error {
+	principal, err := principalFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		return apperror.NewAppError(apperror.ErrorGetUrlParams, "could not get room id")
+	}
+
+	var payload RenameRoomDTO
+
+	if err = utils.BindAndValidate(c, &payload); err != nil {
+		return apperror.NewValidationError(err)
+	}
+
+	err = roomController.roomUsecase.RenameRoom(c.Request().Context(), principal, id, payload.Name, payload.Topic)
+	if err != nil {
+		return err
+	}
+
+	return utils.Negotiate(c, http.StatusOK, map[string]string{"id": id})
+}
+
+func (roomController *RoomController) ArchiveRoom(c echo.Context) error {
+	principal, err := principalFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		return apperror.NewAppError(apperror.ErrorGetUrlParams, "could not get room id")
+	}
+
+	err = roomController.roomUsecase.ArchiveRoom(c.Request().Context(), principal, id)
+	if err != nil {
+		return err
+	}
+
+	return utils.Negotiate(c, http.StatusOK, map[string]string{"id": id})
+}
+
+func (roomController *RoomController) DeleteRoom(c echo.Context) error {
+	principal, err := principalFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		return apperror.NewAppError(apperror.ErrorGetUrlParams, "could not get room id")
+	}
+
+	err = roomController.roomUsecase.DeleteRoom(c.Request().Context(), principal, id)
+	if err != nil {
+		return err
+	}
+
+	return utils.Negotiate(c, http.StatusOK, map[string]string{"id": id})
+}
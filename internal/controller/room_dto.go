@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/Meystergod/gochat/internal/domain"
+)
+
+type CreateRoomDTO struct {
+	Name    string   `json:"name" validate:"required,min=1,max=100"`
+	Topic   string   `json:"topic" validate:"max=500"`
+	Type    string   `json:"type" validate:"required,oneof=public private direct"`
+	Members []string `json:"members" validate:"dive,mongodb"`
+}
+
+type RenameRoomDTO struct {
+	Name  string `json:"name" validate:"required,min=1,max=100"`
+	Topic string `json:"topic" validate:"max=500"`
+}
+
+type RoomResponseDTO struct {
+	ID         string     `json:"id" xml:"id"`
+	Name       string     `json:"name" xml:"name"`
+	Topic      string     `json:"topic" xml:"topic"`
+	Type       string     `json:"type" xml:"type"`
+	OwnerID    string     `json:"owner_id" xml:"owner_id"`
+	Members    []string   `json:"members" xml:"members>member"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty" xml:"archived_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" xml:"created_at"`
+}
+
+func (createRoomDTO *CreateRoomDTO) ToModel() *domain.Room {
+	return &domain.Room{
+		Name:    createRoomDTO.Name,
+		Topic:   createRoomDTO.Topic,
+		Type:    createRoomDTO.Type,
+		Members: createRoomDTO.Members,
+	}
+}
+
+func NewRoomResponseDTO(room *domain.Room) RoomResponseDTO {
+	return RoomResponseDTO{
+		ID:         room.ID,
+		Name:       room.Name,
+		Topic:      room.Topic,
+		Type:       room.Type,
+		OwnerID:    room.OwnerID,
+		Members:    room.Members,
+		ArchivedAt: room.ArchivedAt,
+		CreatedAt:  room.CreatedAt,
+	}
+}
+
+func NewRoomResponseDTOs(rooms []domain.Room) []RoomResponseDTO {
+	responses := make([]RoomResponseDTO, 0, len(rooms))
+	for i := range rooms {
+		responses = append(responses, NewRoomResponseDTO(&rooms[i]))
+	}
+
+	return responses
+}
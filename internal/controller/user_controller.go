@@ -2,6 +2,9 @@ package controller
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Meystergod/gochat/internal/apperror"
 	"github.com/Meystergod/gochat/internal/domain"
@@ -23,7 +26,7 @@ func (userController *UserController) Signup(c echo.Context) error {
 	var payload CreateUserDTO
 
 	if err := utils.BindAndValidate(c, &payload); err != nil {
-		return apperror.NewAppError(apperror.ErrorValidatePayload, err.Error())
+		return apperror.NewValidationError(err)
 	}
 
 	createdUserID, err := userController.userUsecase.Signup(c.Request().Context(), payload.ToModel())
@@ -45,20 +48,27 @@ func (userController *UserController) GetUserInfo(c echo.Context) error {
 		return err
 	}
 
-	return utils.Negotiate(c, http.StatusOK, map[string]domain.User{"user": *user})
+	setETag(c, user.Version)
+
+	if !noneMatch(c, user.Version) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return utils.Negotiate(c, http.StatusOK, map[string]UserResponseDTO{"user": NewUserResponseDTO(user)})
 }
 
-func (userController *UserController) GetAllUsersInfo(c echo.Context) error {
-	users, err := userController.userUsecase.GetAllUsersInfo(c.Request().Context())
+func (userController *UserController) GetUsersInfo(c echo.Context) error {
+	query, err := userQueryFromRequest(c)
 	if err != nil {
 		return err
 	}
 
-	if len(*users) == 0 {
-		return utils.Negotiate(c, http.StatusOK, map[string]string{"users": "list is empty"})
+	page, err := userController.userUsecase.GetUsersInfo(c.Request().Context(), query)
+	if err != nil {
+		return err
 	}
 
-	return utils.Negotiate(c, http.StatusOK, map[string][]domain.User{"users": *users})
+	return utils.Negotiate(c, http.StatusOK, NewUserPageResponseDTO(page))
 }
 
 func (userController *UserController) UpdateUserInfo(c echo.Context) error {
@@ -70,30 +80,139 @@ func (userController *UserController) UpdateUserInfo(c echo.Context) error {
 	var payload UpdateUserDTO
 
 	if err := utils.BindAndValidate(c, &payload); err != nil {
-		return apperror.NewAppError(apperror.ErrorValidatePayload, err.Error())
+		return apperror.NewValidationError(err)
+	}
+
+	ifMatch, err := ifMatchVersions(c)
+	if err != nil {
+		return err
 	}
 
 	user := payload.ToModel()
 	user.ID = id
 
-	err := userController.userUsecase.UpdateUserInfo(c.Request().Context(), user)
+	user, err = userController.userUsecase.UpdateUserInfo(c.Request().Context(), user, ifMatch)
 	if err != nil {
 		return err
 	}
 
+	setETag(c, user.Version)
+
 	return utils.Negotiate(c, http.StatusCreated, map[string]string{"id": id})
 }
 
+func (userController *UserController) PatchUserInfo(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return apperror.NewAppError(apperror.ErrorGetUrlParams, "could not get user id")
+	}
+
+	var payload PatchUserDTO
+
+	if err := bindMergePatch(c, &payload); err != nil {
+		return err
+	}
+
+	payload.Normalize()
+
+	if err := c.Validate(&payload); err != nil {
+		return apperror.NewValidationError(err)
+	}
+
+	ifMatch, err := ifMatchVersions(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := userController.userUsecase.PatchUserInfo(c.Request().Context(), id, payload.ToModel(), ifMatch)
+	if err != nil {
+		return err
+	}
+
+	setETag(c, user.Version)
+
+	return utils.Negotiate(c, http.StatusOK, map[string]UserResponseDTO{"user": NewUserResponseDTO(user)})
+}
+
+func (userController *UserController) RestoreUserAccount(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return apperror.NewAppError(apperror.ErrorGetUrlParams, "could not get user id")
+	}
+
+	user, err := userController.userUsecase.RestoreUserAccount(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+
+	setETag(c, user.Version)
+
+	return utils.Negotiate(c, http.StatusOK, map[string]UserResponseDTO{"user": NewUserResponseDTO(user)})
+}
+
 func (userController *UserController) DeleteUserAccount(c echo.Context) error {
 	id := c.Param("id")
 	if id == "" {
 		return apperror.NewAppError(apperror.ErrorGetUrlParams, "could not get user id")
 	}
 
-	err := userController.userUsecase.DeleteUserAccount(c.Request().Context(), id)
+	ifMatch, err := ifMatchVersions(c)
+	if err != nil {
+		return err
+	}
+
+	err = userController.userUsecase.DeleteUserAccount(c.Request().Context(), id, ifMatch)
 	if err != nil {
 		return err
 	}
 
 	return utils.Negotiate(c, http.StatusCreated, map[string]string{"id": id})
 }
+
+func userQueryFromRequest(c echo.Context) (*domain.UserQuery, error) {
+	query := &domain.UserQuery{
+		NamePrefix:  strings.TrimSpace(c.QueryParam("name")),
+		EmailDomain: strings.ToLower(strings.TrimSpace(c.QueryParam("email_domain"))),
+		Sort:        c.QueryParam("sort"),
+		Cursor:      c.QueryParam("cursor"),
+	}
+
+	if strings.HasPrefix(query.Sort, "-") {
+		query.Sort = strings.TrimPrefix(query.Sort, "-")
+		query.Descending = true
+	}
+
+	var err error
+
+	if value := c.QueryParam("limit"); value != "" {
+		query.Limit, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, apperror.NewAppError(apperror.ErrorGetUrlParams, "limit must be an integer")
+		}
+	}
+
+	if value := c.QueryParam("page"); value != "" {
+		query.Page, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, apperror.NewAppError(apperror.ErrorGetUrlParams, "page must be an integer")
+		}
+	}
+
+	if value := c.QueryParam("registered_from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, apperror.NewAppError(apperror.ErrorGetUrlParams, "registered_from must be an RFC 3339 timestamp")
+		}
+		query.RegisteredFrom = &from
+	}
+
+	if value := c.QueryParam("registered_to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, apperror.NewAppError(apperror.ErrorGetUrlParams, "registered_to must be an RFC 3339 timestamp")
+		}
+		query.RegisteredTo = &to
+	}
+
+	return query, nil
+}
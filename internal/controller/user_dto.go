@@ -1,17 +1,68 @@
 package controller
 
-import "github.com/Meystergod/gochat/internal/domain"
+import (
+	"strings"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/utils"
+)
 
 type CreateUserDTO struct {
 	Name     string `json:"name" validate:"required,min=2"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required,min=6,maxbytes=72"`
 }
 
 type UpdateUserDTO struct {
 	Name     string `json:"name" validate:"required,min=2"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required,min=6,maxbytes=72"`
+}
+
+// PatchUserDTO is a JSON merge patch (RFC 7396) of a user. Absent members are
+// nil and keep their current value.
+type PatchUserDTO struct {
+	Name     *string `json:"name" validate:"omitempty,min=2"`
+	Email    *string `json:"email" validate:"omitempty,email"`
+	Password *string `json:"password" validate:"omitempty,min=6,maxbytes=72"`
+}
+
+type UserResponseDTO struct {
+	ID           string    `json:"id" xml:"id"`
+	Name         string    `json:"name" xml:"name"`
+	Email        string    `json:"email" xml:"email"`
+	Role         string    `json:"role" xml:"role"`
+	RegisteredAt time.Time `json:"registered_at" xml:"registered_at"`
+}
+
+type UserPageResponseDTO struct {
+	Users      []UserResponseDTO `json:"users" xml:"users>user"`
+	Total      int64             `json:"total" xml:"total"`
+	Limit      int               `json:"limit" xml:"limit"`
+	Page       int               `json:"page,omitempty" xml:"page,omitempty"`
+	NextCursor string            `json:"next_cursor,omitempty" xml:"next_cursor,omitempty"`
+}
+
+func (createUserDTO *CreateUserDTO) Normalize() {
+	createUserDTO.Name = strings.TrimSpace(createUserDTO.Name)
+	createUserDTO.Email = utils.NormalizeEmail(createUserDTO.Email)
+}
+
+func (updateUserDTO *UpdateUserDTO) Normalize() {
+	updateUserDTO.Name = strings.TrimSpace(updateUserDTO.Name)
+	updateUserDTO.Email = utils.NormalizeEmail(updateUserDTO.Email)
+}
+
+func (patchUserDTO *PatchUserDTO) Normalize() {
+	if patchUserDTO.Name != nil {
+		name := strings.TrimSpace(*patchUserDTO.Name)
+		patchUserDTO.Name = &name
+	}
+	if patchUserDTO.Email != nil {
+		email := utils.NormalizeEmail(*patchUserDTO.Email)
+		patchUserDTO.Email = &email
+	}
 }
 
 func (createUserDTO *CreateUserDTO) ToModel() *domain.User {
@@ -29,3 +80,40 @@ func (updateUserDTO *UpdateUserDTO) ToModel() *domain.User {
 		Password: updateUserDTO.Password,
 	}
 }
+
+func (patchUserDTO *PatchUserDTO) ToModel() *domain.UserPatch {
+	return &domain.UserPatch{
+		Name:     patchUserDTO.Name,
+		Email:    patchUserDTO.Email,
+		Password: patchUserDTO.Password,
+	}
+}
+
+func NewUserResponseDTO(user *domain.User) UserResponseDTO {
+	return UserResponseDTO{
+		ID:           user.ID,
+		Name:         user.Name,
+		Email:        user.Email,
+		Role:         user.Role,
+		RegisteredAt: user.RegisteredAt,
+	}
+}
+
+func NewUserResponseDTOs(users []domain.User) []UserResponseDTO {
+	responses := make([]UserResponseDTO, 0, len(users))
+	for i := range users {
+		responses = append(responses, NewUserResponseDTO(&users[i]))
+	}
+
+	return responses
+}
+
+func NewUserPageResponseDTO(page *domain.UserPage) UserPageResponseDTO {
+	return UserPageResponseDTO{
+		Users:      NewUserResponseDTOs(page.Users),
+		Total:      page.Total,
+		Limit:      page.Limit,
+		Page:       page.Page,
+		NextCursor: page.NextCursor,
+	}
+}
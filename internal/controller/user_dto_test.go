@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/utils"
+)
+
+func TestPasswordLengthValidation(t *testing.T) {
+	// 25 runes of 3 bytes each, well within 72 characters but over the
+	// 72 bytes bcrypt accepts
+	multibyte := strings.Repeat("€", 25)
+	exact := strings.Repeat("a", 72)
+	tooLong := strings.Repeat("a", 73)
+
+	tests := []struct {
+		name    string
+		payload interface{}
+		wantErr bool
+	}{
+		{name: "create at the limit", payload: &CreateUserDTO{Name: "alice", Email: "alice@example.com", Password: exact}},
+		{name: "create over the limit", payload: &CreateUserDTO{Name: "alice", Email: "alice@example.com", Password: tooLong}, wantErr: true},
+		{name: "create multibyte over the limit", payload: &CreateUserDTO{Name: "alice", Email: "alice@example.com", Password: multibyte}, wantErr: true},
+		{name: "update multibyte over the limit", payload: &UpdateUserDTO{Name: "alice", Email: "alice@example.com", Password: multibyte}, wantErr: true},
+		{name: "patch multibyte over the limit", payload: &PatchUserDTO{Password: &multibyte}, wantErr: true},
+		{name: "patch without password", payload: &PatchUserDTO{}},
+		{name: "reset multibyte over the limit", payload: &ResetPasswordDTO{Token: "token", Password: multibyte}, wantErr: true},
+		{name: "reset multibyte within the limit", payload: &ResetPasswordDTO{Token: "token", Password: strings.Repeat("€", 24)}},
+	}
+
+	validator := utils.NewValidator()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.Validate(tt.payload)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			appError := apperror.NewValidationError(err)
+			if !errors.Is(appError, apperror.ErrorValidatePayload) || appError.Kind().Status() != http.StatusUnprocessableEntity {
+				t.Fatalf("got %v, want %v", appError, apperror.ErrorValidatePayload)
+			}
+
+			if len(appError.Details) != 1 || appError.Details[0].Field != "password" || appError.Details[0].Rule != "maxbytes" {
+				t.Fatalf("got details %+v, want a maxbytes error on password", appError.Details)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_verification"
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+type VerificationController struct {
+	verificationUsecase *usecase_verification.VerificationUsecase
+}
+
+func NewVerificationController(verificationUsecase *usecase_verification.VerificationUsecase) *VerificationController {
+	return &VerificationController{verificationUsecase: verificationUsecase}
+}
+
+func (verificationController *VerificationController) Verify(c echo.Context) error {
+	rawToken := c.QueryParam("token")
+	if rawToken == "" {
+		return apperror.NewAppError(apperror.ErrorGetUrlParams, "could not get verification token")
+	}
+
+	userID, err := verificationController.verificationUsecase.Verify(c.Request().Context(), rawToken)
+	if err != nil {
+		return err
+	}
+
+	return utils.Negotiate(c, http.StatusOK, map[string]string{"id": userID, "status": "verified"})
+}
+
+func (verificationController *VerificationController) ResendVerification(c echo.Context) error {
+	var payload ResendVerificationDTO
+
+	if err := utils.BindAndValidate(c, &payload); err != nil {
+		return apperror.NewValidationError(err)
+	}
+
+	verificationController.verificationUsecase.ResendVerification(c.Request().Context(), payload.Email)
+
+	return c.NoContent(http.StatusAccepted)
+}
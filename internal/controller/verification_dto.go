@@ -0,0 +1,13 @@
+package controller
+
+import (
+	"github.com/Meystergod/gochat/internal/utils"
+)
+
+type ResendVerificationDTO struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (resendVerificationDTO *ResendVerificationDTO) Normalize() {
+	resendVerificationDTO.Email = utils.NormalizeEmail(resendVerificationDTO.Email)
+}
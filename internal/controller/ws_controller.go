@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/broker"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/realtime"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_message"
+	"github.com/Meystergod/gochat/internal/usecase/usecase_room"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+type WebSocketControllerDeps struct {
+	Hub            *realtime.Hub
+	Broker         broker.Broker
+	Upgrader       *websocket.Upgrader
+	ClientConfig   realtime.ClientConfig
+	RoomUsecase    *usecase_room.RoomUsecase
+	MessageUsecase *usecase_message.MessageUsecase
+}
+
+type WebSocketController struct {
+	hub            *realtime.Hub
+	broker         broker.Broker
+	upgrader       *websocket.Upgrader
+	clientConfig   realtime.ClientConfig
+	roomUsecase    *usecase_room.RoomUsecase
+	messageUsecase *usecase_message.MessageUsecase
+}
+
+func NewWebSocketController(deps *WebSocketControllerDeps) *WebSocketController {
+	return &WebSocketController{
+		hub:            deps.Hub,
+		broker:         deps.Broker,
+		upgrader:       deps.Upgrader,
+		clientConfig:   deps.ClientConfig,
+		roomUsecase:    deps.RoomUsecase,
+		messageUsecase: deps.MessageUsecase,
+	}
+}
+
+func (wsController *WebSocketController) Connect(c echo.Context) error {
+	principal, err := principalFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	logger := zerolog.Ctx(ctx)
+
+	conn, err := wsController.upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		// the upgrader has already answered the request with an http error
+		logger.Debug().Err(err).Msg("upgrade websocket connection")
+		return nil
+	}
+
+	client := realtime.NewClient(conn, principal, wsController.clientConfig)
+
+	go client.WritePump()
+
+	if err = wsController.hub.Register(client); err != nil {
+		client.Close(websocket.CloseTryAgainLater, "server shutting down")
+		return nil
+	}
+
+	logger.Debug().Str("user_id", principal.UserID).Msg("websocket client connected")
+
+	err = client.ReadPump(func(command *realtime.Command) {
+		wsController.handleCommand(ctx, client, command)
+	})
+	if err != nil {
+		logger.Debug().Err(err).Str("user_id", principal.UserID).Msg("websocket client read")
+	}
+
+	for _, roomID := range wsController.hub.Unregister(client) {
+		wsController.publish(ctx, domain.NewMemberEvent(domain.EventMemberLeft, roomID, principal.UserID))
+	}
+
+	logger.Debug().Str("user_id", principal.UserID).Msg("websocket client disconnected")
+
+	return nil
+}
+
+func (wsController *WebSocketController) handleCommand(ctx context.Context, client *realtime.Client, command *realtime.Command) {
+	principal := client.Principal()
+
+	switch command.Type {
+	case realtime.CommandPing:
+		client.Enqueue(&realtime.Frame{Type: realtime.FramePong, ID: command.ID})
+	case realtime.CommandJoin:
+		if _, err := wsController.roomUsecase.GetRoom(ctx, principal, command.RoomID); err != nil {
+			client.Enqueue(newCommandErrorFrame(command.ID, err))
+			return
+		}
+
+		if wsController.hub.Join(client, command.RoomID) {
+			wsController.publish(ctx, domain.NewMemberEvent(domain.EventMemberJoined, command.RoomID, principal.UserID))
+		}
+
+		client.Enqueue(realtime.NewAckFrame(command.ID, nil))
+	case realtime.CommandLeave:
+		if wsController.hub.Leave(client, command.RoomID) {
+			wsController.publish(ctx, domain.NewMemberEvent(domain.EventMemberLeft, command.RoomID, principal.UserID))
+		}
+
+		client.Enqueue(realtime.NewAckFrame(command.ID, nil))
+	case realtime.CommandSend:
+		message, err := wsController.messageUsecase.SendMessage(ctx, principal, command.RoomID, command.Body)
+		if err != nil {
+			client.Enqueue(newCommandErrorFrame(command.ID, err))
+			return
+		}
+
+		client.Enqueue(realtime.NewAckFrame(command.ID, NewMessageResponseDTO(message)))
+	default:
+		client.Enqueue(realtime.NewErrorFrame(command.ID, realtime.CodeUnknownCommand, "unknown command type"))
+	}
+}
+
+func (wsController *WebSocketController) publish(ctx context.Context, event *domain.Event) {
+	if err := wsController.broker.Publish(ctx, event); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("room_id", event.RoomID).Msg("publish membership event")
+	}
+}
+
+func newCommandErrorFrame(id string, err error) *realtime.Frame {
+	var appError *apperror.AppError
+	if errors.As(err, &appError) {
+		return realtime.NewErrorFrame(id, appError.Kind().Code(), appError.Message)
+	}
+
+	return realtime.NewErrorFrame(id, apperror.ErrorInternal.Code(), apperror.ErrorInternal.Error())
+}
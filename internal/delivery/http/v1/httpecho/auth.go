@@ -0,0 +1,16 @@
+package httpecho
+
+import (
+	"github.com/Meystergod/gochat/internal/controller"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SetAuthApiRoutes(e *echo.Echo, authController *controller.AuthController, rateLimits *RateLimits) {
+	v1 := e.Group("/api/v1", rateLimits.Strict())
+	{
+		v1.POST("/login", authController.Login)
+		v1.POST("/token/refresh", authController.RefreshToken)
+		v1.POST("/logout", authController.Logout)
+	}
+}
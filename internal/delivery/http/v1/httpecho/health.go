@@ -0,0 +1,13 @@
+package httpecho
+
+import (
+	"github.com/Meystergod/gochat/internal/controller"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SetHealthApiRoutes(e *echo.Echo, healthController *controller.HealthController) {
+	e.GET("/healthz", healthController.Liveness)
+	e.GET("/readyz", healthController.Readiness)
+	e.GET("/health", healthController.Health)
+}
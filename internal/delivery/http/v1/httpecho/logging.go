@@ -0,0 +1,79 @@
+package httpecho
+
+import (
+	"time"
+
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+const maxRequestIDLength = 128
+
+// RequestLoggerMiddleware propagates the X-Request-ID of the request, or
+// assigns a new one, and stores a child logger annotated with the request id,
+// method and route in the request context, so that usecases and repositories
+// log through zerolog.Ctx with the request attached. The child logger derives
+// from the one already in the request context, falling back to logger. Every
+// request is logged once it has been served, with the status of the response.
+func RequestLoggerMiddleware(logger *zerolog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			started := time.Now()
+			request := c.Request()
+
+			requestID := request.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(requestID) {
+				requestID = uuid.NewString()
+			}
+
+			c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+
+			parent := zerolog.Ctx(request.Context())
+			if parent.GetLevel() == zerolog.Disabled {
+				parent = logger
+			}
+
+			requestLogger := parent.With().
+				Str("request_id", requestID).
+				Str("method", request.Method).
+				Str("route", c.Path()).
+				Logger()
+
+			c.SetRequest(request.WithContext(requestLogger.WithContext(request.Context())))
+
+			err := next(c)
+
+			event := requestLogger.Info().
+				Int("status", c.Response().Status).
+				Dur("latency", time.Since(started)).
+				Int64("bytes", c.Response().Size)
+
+			if principal, ok := utils.PrincipalFromContext(c.Request().Context()); ok {
+				event = event.Str("user_id", principal.UserID)
+			}
+
+			event.Msg("served http request")
+
+			return err
+		}
+	}
+}
+
+// validRequestID accepts only short printable ids, because the value ends up
+// verbatim in logs and response headers.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range requestID {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,17 @@
+package httpecho
+
+import (
+	"github.com/Meystergod/gochat/internal/controller"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SetMessageApiRoutes(e *echo.Echo, messageController *controller.MessageController, authMiddleware echo.MiddlewareFunc, rateLimits *RateLimits) {
+	messages := e.Group("/api/v1/rooms/:id/messages", authMiddleware, rateLimits.Default())
+	{
+		messages.POST("", messageController.SendMessage)
+		messages.GET("", messageController.GetHistory)
+		messages.PUT("/:message_id", messageController.EditMessage)
+		messages.DELETE("/:message_id", messageController.DeleteMessage)
+	}
+}
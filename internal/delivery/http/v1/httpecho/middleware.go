@@ -0,0 +1,160 @@
+package httpecho
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/controller"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/httpserver"
+	"github.com/Meystergod/gochat/pkg/token"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/rs/zerolog"
+)
+
+const bearerPrefix = "Bearer "
+
+type AccessTokenParser interface {
+	Parse(tokenString string) (*token.Claims, error)
+}
+
+const accessTokenQueryParam = "access_token"
+
+// AuthMiddleware resolves the caller from the bearer access token and stores
+// it in the request context.
+func AuthMiddleware(parser AccessTokenParser) echo.MiddlewareFunc {
+	return authMiddleware(parser, false)
+}
+
+// WebSocketAuthMiddleware is AuthMiddleware that also accepts the token in the
+// access_token query parameter, because browsers can not set headers on a
+// websocket handshake.
+func WebSocketAuthMiddleware(parser AccessTokenParser) echo.MiddlewareFunc {
+	return authMiddleware(parser, true)
+}
+
+func authMiddleware(parser AccessTokenParser, allowQuery bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			accessToken := ""
+
+			header := c.Request().Header.Get(echo.HeaderAuthorization)
+			switch {
+			case strings.HasPrefix(header, bearerPrefix):
+				accessToken = strings.TrimPrefix(header, bearerPrefix)
+			case allowQuery:
+				accessToken = c.QueryParam(accessTokenQueryParam)
+			}
+
+			if accessToken == "" {
+				return apperror.NewAppError(apperror.ErrorUnauthorized, "missing bearer access token")
+			}
+
+			claims, err := parser.Parse(accessToken)
+			if err != nil {
+				return apperror.NewAppError(apperror.ErrorToken, err.Error())
+			}
+
+			principal := &domain.Principal{
+				UserID: claims.Subject,
+				Role:   claims.Role,
+			}
+
+			ctx := utils.ContextWithPrincipal(c.Request().Context(), principal)
+
+			// from here on everything logged for the request names the caller
+			logger := zerolog.Ctx(ctx).With().Str("user_id", principal.UserID).Logger()
+			ctx = logger.WithContext(ctx)
+
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
+
+// OwnerOrAdminMiddleware allows the request only when the path parameter
+// identifies the caller itself or the caller has the admin role.
+func OwnerOrAdminMiddleware(param string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, ok := utils.PrincipalFromContext(c.Request().Context())
+			if !ok {
+				return apperror.NewAppError(apperror.ErrorUnauthorized, "caller is not authenticated")
+			}
+
+			if !principal.IsAdmin() && principal.UserID != c.Param(param) {
+				return apperror.NewAppError(apperror.ErrorForbidden, "caller can manage only its own account")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// AdminMiddleware allows the request only for callers with the admin role.
+func AdminMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, ok := utils.PrincipalFromContext(c.Request().Context())
+			if !ok {
+				return apperror.NewAppError(apperror.ErrorUnauthorized, "caller is not authenticated")
+			}
+
+			if !principal.IsAdmin() {
+				return apperror.NewAppError(apperror.ErrorForbidden, "caller must be an admin")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// IfMatchRequiredMiddleware rejects, while required is set, writes that are
+// not conditional on the entity tag of the object, so that clients can not
+// blindly overwrite concurrent changes.
+func IfMatchRequiredMiddleware(required *atomic.Bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if required.Load() && c.Request().Header.Get(controller.HeaderIfMatch) == "" {
+				return apperror.NewAppError(apperror.ErrorPreconditionRequired, "request must be conditional on If-Match")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// CORSMiddleware answers cross-origin requests from the allowed origins. While
+// no origins are allowed, no CORS headers are sent and browsers keep the api
+// same-origin.
+func CORSMiddleware(origins *httpserver.Origins) echo.MiddlewareFunc {
+	return middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOriginFunc: func(origin string) (bool, error) {
+			return origins.Allowed(origin), nil
+		},
+		AllowHeaders:  []string{echo.HeaderAuthorization, echo.HeaderContentType, controller.HeaderIfMatch, controller.HeaderIfNoneMatch, echo.HeaderXRequestID},
+		ExposeHeaders: []string{controller.HeaderETag, echo.HeaderXRequestID, echo.HeaderRetryAfter, HeaderRateLimitLimit, HeaderRateLimitRemaining, HeaderRateLimitReset},
+	})
+}
+
+// ErrorResponseMiddleware hands handler errors to the echo error handler and
+// reports success upwards. Middlewares that observe the response, such as
+// tracing, request logging and metrics, only see the status actually sent when
+// the error has been turned into a response already, so it has to be the
+// innermost of them.
+func ErrorResponseMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			return nil
+		}
+	}
+}
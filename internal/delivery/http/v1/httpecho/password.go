@@ -0,0 +1,15 @@
+package httpecho
+
+import (
+	"github.com/Meystergod/gochat/internal/controller"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SetPasswordApiRoutes(e *echo.Echo, passwordController *controller.PasswordController, rateLimits *RateLimits) {
+	v1 := e.Group("/api/v1", rateLimits.Strict())
+	{
+		v1.POST("/password/forgot", passwordController.ForgotPassword)
+		v1.POST("/password/reset", passwordController.ResetPassword)
+	}
+}
@@ -0,0 +1,137 @@
+package httpecho
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/ratelimit"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+)
+
+type RateLimitPolicies struct {
+	Strict ratelimit.Policy
+	Read   ratelimit.Policy
+	Write  ratelimit.Policy
+}
+
+// RateLimits builds the rate limiting middlewares of the api. Callers are
+// identified by user when authenticated and by client ip otherwise, and every
+// policy has its own budget. When the store fails the request is let through,
+// so that an outage of the store does not take the api down. The policies can
+// be replaced while the server is running.
+type RateLimits struct {
+	store  ratelimit.Store
+	state  atomic.Pointer[rateLimitState]
+	logger *zerolog.Logger
+}
+
+type rateLimitState struct {
+	policies *RateLimitPolicies
+	enabled  bool
+}
+
+func NewRateLimits(ctx context.Context, store ratelimit.Store, policies *RateLimitPolicies, enabled bool) (*RateLimits, error) {
+	r := &RateLimits{
+		store:  store,
+		logger: zerolog.Ctx(ctx),
+	}
+
+	if err := r.Update(policies, enabled); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Update atomically replaces the policies and turns limiting on or off for
+// the requests that follow.
+func (r *RateLimits) Update(policies *RateLimitPolicies, enabled bool) error {
+	if enabled {
+		for _, policy := range []ratelimit.Policy{policies.Strict, policies.Read, policies.Write} {
+			if err := policy.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	r.state.Store(&rateLimitState{policies: policies, enabled: enabled})
+
+	return nil
+}
+
+// Strict limits sensitive endpoints such as signup and login, which are
+// targets of brute force and enumeration.
+func (r *RateLimits) Strict() echo.MiddlewareFunc {
+	return r.middleware(func(_ echo.Context, policies *RateLimitPolicies) ratelimit.Policy {
+		return policies.Strict
+	})
+}
+
+// Default limits reads and writes with separate budgets.
+func (r *RateLimits) Default() echo.MiddlewareFunc {
+	return r.middleware(func(c echo.Context, policies *RateLimitPolicies) ratelimit.Policy {
+		switch c.Request().Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return policies.Read
+		default:
+			return policies.Write
+		}
+	})
+}
+
+func (r *RateLimits) middleware(policyFor func(c echo.Context, policies *RateLimitPolicies) ratelimit.Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			state := r.state.Load()
+			if !state.enabled {
+				return next(c)
+			}
+
+			policy := policyFor(c, state.policies)
+
+			result, err := r.store.Take(c.Request().Context(), rateLimitKey(c, policy), policy, time.Now())
+			if err != nil {
+				r.logger.Error().Err(err).Str("policy", policy.Name).Msg("take rate limit token")
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+			header.Set(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+			header.Set(HeaderRateLimitReset, ceilSeconds(result.Reset))
+
+			if !result.Allowed {
+				header.Set(echo.HeaderRetryAfter, ceilSeconds(result.RetryAfter))
+				return apperror.NewAppError(apperror.ErrorRateLimited, "too many requests, retry later")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func rateLimitKey(c echo.Context, policy ratelimit.Policy) string {
+	if principal, ok := utils.PrincipalFromContext(c.Request().Context()); ok {
+		return policy.Name + ":user:" + principal.UserID
+	}
+
+	return policy.Name + ":ip:" + c.RealIP()
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
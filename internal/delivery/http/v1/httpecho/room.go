@@ -0,0 +1,20 @@
+package httpecho
+
+import (
+	"github.com/Meystergod/gochat/internal/controller"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SetRoomApiRoutes(e *echo.Echo, roomController *controller.RoomController, eventController *controller.EventController, authMiddleware echo.MiddlewareFunc, rateLimits *RateLimits) {
+	rooms := e.Group("/api/v1/rooms", authMiddleware, rateLimits.Default())
+	{
+		rooms.POST("", roomController.CreateRoom)
+		rooms.GET("", roomController.GetRooms)
+		rooms.GET("/:id", roomController.GetRoom)
+		rooms.PUT("/:id", roomController.RenameRoom)
+		rooms.POST("/:id/archive", roomController.ArchiveRoom)
+		rooms.DELETE("/:id", roomController.DeleteRoom)
+		rooms.GET("/:id/events", eventController.StreamRoomEvents)
+	}
+}
@@ -1,18 +1,28 @@
 package httpecho
 
 import (
+	"sync/atomic"
+
 	"github.com/Meystergod/gochat/internal/controller"
 
 	"github.com/labstack/echo/v4"
 )
 
-func SetUserApiRoutes(e *echo.Echo, userController *controller.UserController) {
+func SetUserApiRoutes(e *echo.Echo, userController *controller.UserController, authMiddleware echo.MiddlewareFunc, rateLimits *RateLimits, requireIfMatch *atomic.Bool) {
+	writeMiddlewares := []echo.MiddlewareFunc{OwnerOrAdminMiddleware("id"), IfMatchRequiredMiddleware(requireIfMatch)}
+
 	v1 := e.Group("/api/v1")
 	{
-		v1.POST("/signup", userController.Signup)
-		v1.GET("/user/:id", userController.GetUserInfo)
-		v1.GET("/users", userController.GetAllUsersInfo)
-		v1.PUT("/user/:id", userController.UpdateUserInfo)
-		v1.DELETE("/user/:id", userController.DeleteUserAccount)
+		v1.POST("/signup", userController.Signup, rateLimits.Strict())
+	}
+
+	private := e.Group("/api/v1", authMiddleware, rateLimits.Default())
+	{
+		private.GET("/user/:id", userController.GetUserInfo)
+		private.GET("/users", userController.GetUsersInfo)
+		private.PUT("/user/:id", userController.UpdateUserInfo, writeMiddlewares...)
+		private.PATCH("/user/:id", userController.PatchUserInfo, writeMiddlewares...)
+		private.DELETE("/user/:id", userController.DeleteUserAccount, writeMiddlewares...)
+		private.POST("/user/:id/restore", userController.RestoreUserAccount, AdminMiddleware())
 	}
 }
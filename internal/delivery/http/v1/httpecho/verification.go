@@ -0,0 +1,15 @@
+package httpecho
+
+import (
+	"github.com/Meystergod/gochat/internal/controller"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SetVerificationApiRoutes(e *echo.Echo, verificationController *controller.VerificationController, rateLimits *RateLimits) {
+	v1 := e.Group("/api/v1", rateLimits.Strict())
+	{
+		v1.GET("/verify", verificationController.Verify)
+		v1.POST("/verify/resend", verificationController.ResendVerification)
+	}
+}
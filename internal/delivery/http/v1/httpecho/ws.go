@@ -0,0 +1,14 @@
+package httpecho
+
+import (
+	"github.com/Meystergod/gochat/internal/controller"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SetWebSocketApiRoutes(e *echo.Echo, wsController *controller.WebSocketController, wsAuthMiddleware echo.MiddlewareFunc, rateLimits *RateLimits) {
+	v1 := e.Group("/api/v1")
+	{
+		v1.GET("/ws", wsController.Connect, wsAuthMiddleware, rateLimits.Default())
+	}
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"time"
+)
+
+const (
+	EventMessageCreated = "message.created"
+	EventMessageEdited  = "message.edited"
+	EventMessageDeleted = "message.deleted"
+	EventMemberJoined   = "member.joined"
+	EventMemberLeft     = "member.left"
+
+	// EventStreamReset tells a resuming client that events were skipped, so
+	// it has to reload the room instead of relying on the stream.
+	EventStreamReset = "stream.reset"
+)
+
+// Event is a room scoped notification delivered to real-time subscribers.
+// Message events carry the id of the message they describe, which doubles as
+// a resume position in persisted history.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	RoomID    string    `json:"room_id"`
+	UserID    string    `json:"user_id,omitempty"`
+	Message   *Message  `json:"message,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewMessageEvent(eventType string, message *Message) *Event {
+	return &Event{
+		ID:        message.ID,
+		Type:      eventType,
+		RoomID:    message.RoomID,
+		UserID:    message.AuthorID,
+		Message:   message,
+		CreatedAt: time.Now(),
+	}
+}
+
+func NewMemberEvent(eventType, roomID, userID string) *Event {
+	return &Event{
+		Type:      eventType,
+		RoomID:    roomID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+}
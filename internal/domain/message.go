@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"time"
+)
+
+type Message struct {
+	ID        string     `json:"id"`
+	RoomID    string     `json:"room_id"`
+	AuthorID  string     `json:"author_id"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func (m *Message) IsDeleted() bool {
+	return m.DeletedAt != nil
+}
+
+// MessageCursor selects a bounded page of room history. Before and After are
+// message ids; at most one of them is expected to be set.
+type MessageCursor struct {
+	RoomID string
+	Before string
+	After  string
+	Limit  int
+}
+
+type MessagePage struct {
+	Messages   []Message
+	HasMore    bool
+	NextBefore string
+	NextAfter  string
+}
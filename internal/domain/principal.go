@@ -0,0 +1,11 @@
+package domain
+
+// Principal is the authenticated caller resolved from an access token.
+type Principal struct {
+	UserID string
+	Role   string
+}
+
+func (p *Principal) IsAdmin() bool {
+	return p.Role == RoleAdmin
+}
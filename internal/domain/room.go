@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"time"
+)
+
+const (
+	RoomTypePublic  = "public"
+	RoomTypePrivate = "private"
+	RoomTypeDirect  = "direct"
+)
+
+type Room struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Topic      string     `json:"topic"`
+	Type       string     `json:"type"`
+	OwnerID    string     `json:"owner_id"`
+	Members    []string   `json:"members"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (r *Room) HasMember(userID string) bool {
+	for _, member := range r.Members {
+		if member == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Room) IsArchived() bool {
+	return r.ArchivedAt != nil
+}
+
+type RoomFilter struct {
+	MemberID        string
+	IncludeArchived bool
+}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"time"
+)
+
+type Session struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	FamilyID  string     `json:"family_id"`
+	TokenHash string     `json:"-" xml:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token" xml:"access_token"`
+	RefreshToken string `json:"refresh_token" xml:"refresh_token"`
+	TokenType    string `json:"token_type" xml:"token_type"`
+	ExpiresIn    int64  `json:"expires_in" xml:"expires_in"`
+}
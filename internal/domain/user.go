@@ -4,10 +4,64 @@ import (
 	"time"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID           string    `json:"id"`
-	Name         string    `json:"name"`
-	Email        string    `json:"email"`
-	Password     string    `json:"password"`
-	RegisteredAt time.Time `json:"registered_at"`
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	Password     string     `json:"-" xml:"-"`
+	Role         string     `json:"role"`
+	RegisteredAt time.Time  `json:"registered_at"`
+	Version      int64      `json:"version"`
+	VerifiedAt   *time.Time `json:"verified_at,omitempty"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+}
+
+func (u *User) IsVerified() bool {
+	return u.VerifiedAt != nil
+}
+
+// UserPatch holds the fields of a partial user update. Nil fields are left
+// untouched.
+type UserPatch struct {
+	Name     *string
+	Email    *string
+	Password *string
+}
+
+func (p *UserPatch) IsEmpty() bool {
+	return p.Name == nil && p.Email == nil && p.Password == nil
+}
+
+const (
+	UserSortID           = "id"
+	UserSortName         = "name"
+	UserSortEmail        = "email"
+	UserSortRegisteredAt = "registered_at"
+)
+
+// UserQuery selects a bounded, ordered page of users. When Cursor is set it
+// takes precedence over Page and continues the listing after the cursor.
+type UserQuery struct {
+	NamePrefix     string
+	EmailDomain    string
+	RegisteredFrom *time.Time
+	RegisteredTo   *time.Time
+	Sort           string
+	Descending     bool
+	Limit          int
+	Page           int
+	Cursor         string
+}
+
+type UserPage struct {
+	Users      []User
+	Total      int64
+	Limit      int
+	Page       int
+	NextCursor string
 }
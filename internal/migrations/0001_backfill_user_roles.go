@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/migrate"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// backfillUserRoles stores the implicit user role on accounts created before
+// roles existed.
+var backfillUserRoles = migrate.Migration{
+	Version: 1,
+	Name:    "backfill_user_roles",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		filter := bson.M{"role": bson.M{"$exists": false}}
+		update := bson.M{"$set": bson.M{"role": domain.RoleUser}}
+
+		_, err := db.Collection(utils.CollNameUser).UpdateMany(ctx, filter, update)
+		return err
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		filter := bson.M{"role": domain.RoleUser}
+		update := bson.M{"$unset": bson.M{"role": ""}}
+
+		_, err := db.Collection(utils.CollNameUser).UpdateMany(ctx, filter, update)
+		return err
+	},
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/migrate"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// createSessionIndexes makes refresh token lookups unique and lets Mongo
+// remove sessions once they expire.
+var createSessionIndexes = migrate.Migration{
+	Version: 2,
+	Name:    "create_session_indexes",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		indexes := []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "token_hash", Value: 1}},
+				Options: options.Index().SetName("token_hash_unique").SetUnique(true),
+			},
+			{
+				Keys:    bson.D{{Key: "family_id", Value: 1}},
+				Options: options.Index().SetName("family_id_1"),
+			},
+			{
+				Keys:    bson.D{{Key: "user_id", Value: 1}},
+				Options: options.Index().SetName("user_id_1"),
+			},
+			{
+				Keys:    bson.D{{Key: "expires_at", Value: 1}},
+				Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+			},
+		}
+
+		_, err := db.Collection(utils.CollNameSession).Indexes().CreateMany(ctx, indexes)
+		return err
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		return dropIndexes(ctx, db.Collection(utils.CollNameSession), "token_hash_unique", "family_id_1", "user_id_1", "expires_at_ttl")
+	},
+}
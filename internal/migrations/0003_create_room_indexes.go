@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/migrate"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// createRoomIndexes serves the room listing, which selects public rooms and
+// rooms the caller is a member of.
+var createRoomIndexes = migrate.Migration{
+	Version: 3,
+	Name:    "create_room_indexes",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		indexes := []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "members", Value: 1}},
+				Options: options.Index().SetName("members_1"),
+			},
+			{
+				Keys:    bson.D{{Key: "type", Value: 1}},
+				Options: options.Index().SetName("type_1"),
+			},
+		}
+
+		_, err := db.Collection(utils.CollNameRoom).Indexes().CreateMany(ctx, indexes)
+		return err
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		return dropIndexes(ctx, db.Collection(utils.CollNameRoom), "members_1", "type_1")
+	},
+}
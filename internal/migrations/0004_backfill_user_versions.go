@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/migrate"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// backfillUserVersions gives accounts created before optimistic concurrency
+// control an initial version, so that conditional writes can match them.
+var backfillUserVersions = migrate.Migration{
+	Version: 4,
+	Name:    "backfill_user_versions",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		filter := bson.M{"version": bson.M{"$exists": false}}
+		update := bson.M{"$set": bson.M{"version": int64(1)}}
+
+		_, err := db.Collection(utils.CollNameUser).UpdateMany(ctx, filter, update)
+		return err
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		update := bson.M{"$unset": bson.M{"version": ""}}
+
+		_, err := db.Collection(utils.CollNameUser).UpdateMany(ctx, bson.M{}, update)
+		return err
+	},
+}
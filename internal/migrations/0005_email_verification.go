@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/migrate"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// emailVerification indexes the user token collection and treats accounts
+// created before email verification existed as verified at registration.
+// Down removes verified_at from every account, including accounts verified
+// since, because the schema before this migration has no such field.
+var emailVerification = migrate.Migration{
+	Version: 5,
+	Name:    "email_verification",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		indexes := []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "token_hash", Value: 1}},
+				Options: options.Index().SetName("token_hash_unique").SetUnique(true),
+			},
+			{
+				Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}},
+				Options: options.Index().SetName("user_id_purpose"),
+			},
+			{
+				Keys:    bson.D{{Key: "expires_at", Value: 1}},
+				Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+			},
+		}
+
+		if _, err := db.Collection(utils.CollNameToken).Indexes().CreateMany(ctx, indexes); err != nil {
+			return err
+		}
+
+		filter := bson.M{"verified_at": bson.M{"$exists": false}}
+		update := bson.A{bson.M{"$set": bson.M{"verified_at": bson.M{"$ifNull": bson.A{"$registered_at", "$$NOW"}}}}}
+
+		_, err := db.Collection(utils.CollNameUser).UpdateMany(ctx, filter, update)
+		return err
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		update := bson.M{"$unset": bson.M{"verified_at": ""}}
+
+		if _, err := db.Collection(utils.CollNameUser).UpdateMany(ctx, bson.M{}, update); err != nil {
+			return err
+		}
+
+		return dropIndexes(ctx, db.Collection(utils.CollNameToken), "token_hash_unique", "user_id_purpose", "expires_at_ttl")
+	},
+}
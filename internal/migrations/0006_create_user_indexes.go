@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/migrate"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// createUserIndexes makes emails unique regardless of case and serves the
+// keyset pagination of the user listing and the purge of deleted accounts.
+// The collation must match the one the user repository queries emails with.
+var createUserIndexes = migrate.Migration{
+	Version: 6,
+	Name:    "create_user_indexes",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		indexes := []mongo.IndexModel{
+			{
+				Keys: bson.D{{Key: "email", Value: 1}},
+				Options: options.Index().
+					SetName("email_unique_ci").
+					SetUnique(true).
+					SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+			},
+			{
+				Keys:    bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}},
+				Options: options.Index().SetName("name_id"),
+			},
+			{
+				Keys:    bson.D{{Key: "registered_at", Value: 1}, {Key: "_id", Value: 1}},
+				Options: options.Index().SetName("registered_at_id"),
+			},
+			{
+				Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+				Options: options.Index().SetName("deleted_at").SetSparse(true),
+			},
+		}
+
+		_, err := db.Collection(utils.CollNameUser).Indexes().CreateMany(ctx, indexes)
+		return err
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		return dropIndexes(ctx, db.Collection(utils.CollNameUser), "email_unique_ci", "name_id", "registered_at_id", "deleted_at")
+	},
+}
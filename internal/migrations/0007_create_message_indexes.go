@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/migrate"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// createMessageIndexes serves the message history, where every page is a
+// range scan over (room_id, _id) in either direction.
+var createMessageIndexes = migrate.Migration{
+	Version: 7,
+	Name:    "create_message_indexes",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		indexes := []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "room_id", Value: 1}, {Key: "_id", Value: 1}},
+				Options: options.Index().SetName("room_id_1__id_1"),
+			},
+		}
+
+		_, err := db.Collection(utils.CollNameMessage).Indexes().CreateMany(ctx, indexes)
+		return err
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		return dropIndexes(ctx, db.Collection(utils.CollNameMessage), "room_id_1__id_1")
+	},
+}
@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/migrate"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultEventTTLSeconds is the expiry the broker event index is created with.
+// The broker applies the configured ttl to it with collMod on startup.
+const defaultEventTTLSeconds = 3600
+
+// createExpiryIndexes expires broker events and idle rate limit buckets.
+// Deployments that created the event index before it was migrated may have
+// another expiry on it, which the broker corrects, so that conflict is
+// ignored.
+var createExpiryIndexes = migrate.Migration{
+	Version: 8,
+	Name:    "create_expiry_indexes",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		eventIndex := mongo.IndexModel{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("created_at_ttl").SetExpireAfterSeconds(defaultEventTTLSeconds),
+		}
+
+		_, err := db.Collection(utils.CollNameEvent).Indexes().CreateOne(ctx, eventIndex)
+
+		var commandError mongo.CommandError
+		if err != nil && !(errors.As(err, &commandError) && commandError.Code == codeIndexOptionsConflict) {
+			return err
+		}
+
+		rateLimitIndex := mongo.IndexModel{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+		}
+
+		_, err = db.Collection(utils.CollNameRateLimit).Indexes().CreateOne(ctx, rateLimitIndex)
+		return err
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		if err := dropIndexes(ctx, db.Collection(utils.CollNameEvent), "created_at_ttl"); err != nil {
+			return err
+		}
+
+		return dropIndexes(ctx, db.Collection(utils.CollNameRateLimit), "expires_at_ttl")
+	},
+}
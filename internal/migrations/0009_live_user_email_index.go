@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/migrate"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// liveUserEmailIndex limits the unique email index to live accounts, so that a
+// soft deleted account does not hold on to its email. Partial indexes can not
+// filter on a missing field, so live accounts store deleted_at as null. Down
+// fails while a live and a deleted account share an email.
+var liveUserEmailIndex = migrate.Migration{
+	Version: 9,
+	Name:    "live_user_email_index",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		collection := db.Collection(utils.CollNameUser)
+
+		filter := bson.M{"deleted_at": bson.M{"$exists": false}}
+		update := bson.M{"$set": bson.M{"deleted_at": nil}}
+
+		if _, err := collection.UpdateMany(ctx, filter, update); err != nil {
+			return err
+		}
+
+		if err := dropIndexes(ctx, collection, "email_unique_ci"); err != nil {
+			return err
+		}
+
+		return createEmailIndex(ctx, collection, bson.M{"deleted_at": bson.M{"$type": "null"}})
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		collection := db.Collection(utils.CollNameUser)
+
+		if err := dropIndexes(ctx, collection, "email_unique_ci"); err != nil {
+			return err
+		}
+
+		if err := createEmailIndex(ctx, collection, nil); err != nil {
+			return err
+		}
+
+		filter := bson.M{"deleted_at": bson.M{"$type": "null"}}
+		update := bson.M{"$unset": bson.M{"deleted_at": ""}}
+
+		_, err := collection.UpdateMany(ctx, filter, update)
+		return err
+	},
+}
+
+// createEmailIndex builds the unique email index with the collation of the
+// user repository, limited to the documents matching partialFilter if set.
+func createEmailIndex(ctx context.Context, collection *mongo.Collection, partialFilter interface{}) error {
+	opts := options.Index().
+		SetName("email_unique_ci").
+		SetUnique(true).
+		SetCollation(&options.Collation{Locale: "en", Strength: 2})
+
+	if partialFilter != nil {
+		opts.SetPartialFilterExpression(partialFilter)
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "email", Value: 1}}, Options: opts})
+	return err
+}
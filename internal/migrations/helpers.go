@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	codeIndexNotFound        = 27
+	codeIndexOptionsConflict = 85
+)
+
+func dropIndexes(ctx context.Context, collection *mongo.Collection, names ...string) error {
+	for _, name := range names {
+		_, err := collection.Indexes().DropOne(ctx, name)
+
+		var commandError mongo.CommandError
+		if errors.As(err, &commandError) && commandError.Code == codeIndexNotFound {
+			continue
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "dropping index %s", name)
+		}
+	}
+
+	return nil
+}
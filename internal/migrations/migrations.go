@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"github.com/Meystergod/gochat/pkg/migrate"
+)
+
+// All returns every schema migration of the service. New migrations are
+// appended with the next version number and never renumbered.
+func All() []migrate.Migration {
+	return []migrate.Migration{
+		backfillUserRoles,
+		createSessionIndexes,
+		createRoomIndexes,
+		backfillUserVersions,
+		emailVerification,
+		createUserIndexes,
+		createMessageIndexes,
+		createExpiryIndexes,
+		liveUserEmailIndex,
+	}
+}
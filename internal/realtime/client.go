@@ -0,0 +1,184 @@
+package realtime
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/domain"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+type ClientConfig struct {
+	SendBuffer     int
+	PingInterval   time.Duration
+	PongWait       time.Duration
+	WriteWait      time.Duration
+	MaxMessageSize int64
+}
+
+// Client is a single websocket connection. Outgoing frames are queued on a
+// bounded buffer drained by WritePump; a client that cannot keep up is
+// disconnected instead of blocking the publisher.
+type Client struct {
+	conn      *websocket.Conn
+	principal *domain.Principal
+	cfg       ClientConfig
+
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+}
+
+func NewClient(conn *websocket.Conn, principal *domain.Principal, cfg ClientConfig) *Client {
+	return &Client{
+		conn:      conn,
+		principal: principal,
+		cfg:       cfg,
+		send:      make(chan []byte, cfg.SendBuffer),
+		done:      make(chan struct{}),
+	}
+}
+
+func (c *Client) Principal() *domain.Principal {
+	return c.principal
+}
+
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Enqueue schedules a frame for delivery. It returns false when the client is
+// closed or its buffer is full, in which case the client is closed as a slow
+// consumer.
+func (c *Client) Enqueue(frame *Frame) bool {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return false
+	}
+
+	return c.enqueueRaw(data)
+}
+
+func (c *Client) enqueueRaw(data []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- data:
+		return true
+	default:
+		c.Close(websocket.ClosePolicyViolation, "slow consumer")
+		return false
+	}
+}
+
+// Close asks the write pump to send a close frame with the given code and
+// shut the connection down. Only the first call has an effect.
+func (c *Client) Close(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		close(c.done)
+	})
+}
+
+// WritePump delivers queued frames and heartbeat pings until the client is
+// closed. It owns all writes to the connection.
+func (c *Client) WritePump() {
+	ticker := time.NewTicker(c.cfg.PingInterval)
+
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data := <-c.send:
+			if err := c.write(websocket.TextMessage, data); err != nil {
+				c.Close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			if err := c.write(websocket.PingMessage, nil); err != nil {
+				c.Close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.done:
+			if c.closeCode != websocket.ClosePolicyViolation {
+				c.flush()
+			}
+			if c.closeCode != websocket.CloseAbnormalClosure {
+				message := websocket.FormatCloseMessage(c.closeCode, c.closeText)
+				_ = c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(c.cfg.WriteWait))
+			}
+			return
+		}
+	}
+}
+
+// ReadPump reads commands until the connection fails or is closed and hands
+// every decoded command to handle. Pongs extend the read deadline.
+func (c *Client) ReadPump(handle func(command *Command)) error {
+	c.conn.SetReadLimit(c.cfg.MaxMessageSize)
+
+	if err := c.conn.SetReadDeadline(time.Now().Add(c.cfg.PongWait)); err != nil {
+		return errors.Wrap(err, "setting read deadline")
+	}
+
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.cfg.PongWait))
+	})
+
+	defer c.Close(websocket.CloseNormalClosure, "")
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return errors.Wrap(err, "reading websocket frame")
+			}
+			return nil
+		}
+
+		var command Command
+
+		if err = json.Unmarshal(data, &command); err != nil {
+			c.Enqueue(NewErrorFrame("", CodeMalformedCommand, "malformed command"))
+			continue
+		}
+
+		handle(&command)
+	}
+}
+
+func (c *Client) write(messageType int, data []byte) error {
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteWait)); err != nil {
+		return err
+	}
+
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// flush writes frames that were queued before the client was closed, so that
+// acks and events are not lost on a graceful shutdown.
+func (c *Client) flush() {
+	for {
+		select {
+		case data := <-c.send:
+			if err := c.write(websocket.TextMessage, data); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/Meystergod/gochat/internal/domain"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+var ErrDraining = errors.New("hub is draining connections")
+
+// subscriber receives room events from the hub. frame is the event already
+// encoded as a websocket frame so that it is marshaled once per publish.
+type subscriber interface {
+	deliver(event *domain.Event, frame []byte) bool
+	shutdown()
+}
+
+// Hub tracks connected websocket clients and stream subscriptions and fans
+// room events out to them.
+type Hub struct {
+	mu            sync.RWMutex
+	clients       map[*Client]map[string]struct{}
+	subscriptions map[*Subscription]struct{}
+	rooms         map[string]map[subscriber]struct{}
+	draining      bool
+	wg            sync.WaitGroup
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		clients:       make(map[*Client]map[string]struct{}),
+		subscriptions: make(map[*Subscription]struct{}),
+		rooms:         make(map[string]map[subscriber]struct{}),
+	}
+}
+
+func (h *Hub) Register(client *Client) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.draining {
+		return ErrDraining
+	}
+
+	h.clients[client] = make(map[string]struct{})
+	h.wg.Add(1)
+
+	return nil
+}
+
+// Unregister removes the client from the hub and returns the rooms it was
+// subscribed to.
+func (h *Hub) Unregister(client *Client) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subscriptions, ok := h.clients[client]
+	if !ok {
+		return nil
+	}
+
+	rooms := make([]string, 0, len(subscriptions))
+	for roomID := range subscriptions {
+		h.removeFromRoom(client, roomID)
+		rooms = append(rooms, roomID)
+	}
+
+	delete(h.clients, client)
+	h.wg.Done()
+
+	return rooms
+}
+
+// Join subscribes the client to room events. It reports whether the client
+// was not subscribed before.
+func (h *Hub) Join(client *Client, roomID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subscriptions, ok := h.clients[client]
+	if !ok {
+		return false
+	}
+
+	if _, ok = subscriptions[roomID]; ok {
+		return false
+	}
+
+	subscriptions[roomID] = struct{}{}
+	h.addToRoom(client, roomID)
+
+	return true
+}
+
+// Leave unsubscribes the client from room events. It reports whether the
+// client was subscribed.
+func (h *Hub) Leave(client *Client, roomID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subscriptions, ok := h.clients[client]
+	if !ok {
+		return false
+	}
+
+	if _, ok = subscriptions[roomID]; !ok {
+		return false
+	}
+
+	delete(subscriptions, roomID)
+	h.removeFromRoom(client, roomID)
+
+	return true
+}
+
+// Subscribe opens an event stream for a single room, used by transports that
+// are not websockets. The subscription must be released with Unsubscribe.
+func (h *Hub) Subscribe(roomID string, buffer int) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.draining {
+		return nil, ErrDraining
+	}
+
+	subscription := newSubscription(roomID, buffer)
+
+	h.subscriptions[subscription] = struct{}{}
+	h.addToRoom(subscription, roomID)
+	h.wg.Add(1)
+
+	return subscription, nil
+}
+
+func (h *Hub) Unsubscribe(subscription *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscriptions[subscription]; !ok {
+		return
+	}
+
+	h.removeFromRoom(subscription, subscription.roomID)
+	delete(h.subscriptions, subscription)
+	subscription.shutdown()
+	h.wg.Done()
+}
+
+// Publish delivers the event to every subscriber of its room. Slow
+// subscribers are dropped rather than allowed to stall delivery to the others.
+func (h *Hub) Publish(ctx context.Context, event *domain.Event) error {
+	frame, err := json.Marshal(NewEventFrame(event))
+	if err != nil {
+		return errors.Wrap(err, "marshaling event frame")
+	}
+
+	h.mu.RLock()
+	members := make([]subscriber, 0, len(h.rooms[event.RoomID]))
+	for member := range h.rooms[event.RoomID] {
+		members = append(members, member)
+	}
+	h.mu.RUnlock()
+
+	dropped := 0
+	for _, member := range members {
+		if !member.deliver(event, frame) {
+			dropped++
+		}
+	}
+
+	if dropped > 0 {
+		zerolog.Ctx(ctx).Warn().
+			Str("room_id", event.RoomID).
+			Int("dropped", dropped).
+			Msg("dropped slow event subscribers")
+	}
+
+	return nil
+}
+
+// Shutdown stops accepting subscribers, asks every connected client and
+// stream to go away and waits until all of them have finished or ctx is done.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.draining = true
+	for client := range h.clients {
+		client.shutdown()
+	}
+	for subscription := range h.subscriptions {
+		subscription.shutdown()
+	}
+	h.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "draining event subscribers")
+	}
+}
+
+func (h *Hub) addToRoom(member subscriber, roomID string) {
+	members, ok := h.rooms[roomID]
+	if !ok {
+		members = make(map[subscriber]struct{})
+		h.rooms[roomID] = members
+	}
+
+	members[member] = struct{}{}
+}
+
+func (h *Hub) removeFromRoom(member subscriber, roomID string) {
+	members := h.rooms[roomID]
+	delete(members, member)
+
+	if len(members) == 0 {
+		delete(h.rooms, roomID)
+	}
+}
+
+func (c *Client) deliver(_ *domain.Event, frame []byte) bool {
+	return c.enqueueRaw(frame)
+}
+
+func (c *Client) shutdown() {
+	c.Close(websocket.CloseGoingAway, "server shutting down")
+}
@@ -0,0 +1,180 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/domain"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestClient returns a registered client without a connection. Its pumps
+// are never started, so queued frames stay on the send buffer.
+func newTestClient(t *testing.T, hub *Hub, buffer int) *Client {
+	t.Helper()
+
+	client := NewClient(nil, &domain.Principal{UserID: "user"}, ClientConfig{SendBuffer: buffer})
+	if err := hub.Register(client); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	return client
+}
+
+func publishTestEvent(t *testing.T, hub *Hub, roomID, id string) {
+	t.Helper()
+
+	event := &domain.Event{ID: id, Type: domain.EventMessageCreated, RoomID: roomID}
+	if err := hub.Publish(context.Background(), event); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+}
+
+// queuedEventIDs drains the send buffer of the client and returns the ids of
+// the queued event frames.
+func queuedEventIDs(t *testing.T, client *Client) []string {
+	t.Helper()
+
+	var ids []string
+
+	for {
+		select {
+		case data := <-client.send:
+			var frame Frame
+
+			if err := json.Unmarshal(data, &frame); err != nil {
+				t.Fatalf("decode frame: %v", err)
+			}
+
+			ids = append(ids, frame.Event.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestHubDeliversToJoinedRooms(t *testing.T) {
+	hub := NewHub()
+
+	joined := newTestClient(t, hub, 4)
+	other := newTestClient(t, hub, 4)
+
+	if !hub.Join(joined, "room-1") {
+		t.Fatal("first join reported an existing subscription")
+	}
+
+	if hub.Join(joined, "room-1") {
+		t.Fatal("second join reported a new subscription")
+	}
+
+	hub.Join(other, "room-2")
+
+	publishTestEvent(t, hub, "room-1", "event-1")
+
+	if ids := queuedEventIDs(t, joined); len(ids) != 1 || ids[0] != "event-1" {
+		t.Fatalf("joined client got %v, want [event-1]", ids)
+	}
+
+	if ids := queuedEventIDs(t, other); len(ids) != 0 {
+		t.Fatalf("client of another room got %v", ids)
+	}
+
+	if !hub.Leave(joined, "room-1") {
+		t.Fatal("leave reported no subscription")
+	}
+
+	publishTestEvent(t, hub, "room-1", "event-2")
+
+	if ids := queuedEventIDs(t, joined); len(ids) != 0 {
+		t.Fatalf("client got %v after leaving", ids)
+	}
+}
+
+func TestHubUnregisterReturnsRooms(t *testing.T) {
+	hub := NewHub()
+	client := newTestClient(t, hub, 4)
+
+	hub.Join(client, "room-1")
+	hub.Join(client, "room-2")
+
+	rooms := hub.Unregister(client)
+	if len(rooms) != 2 {
+		t.Fatalf("got rooms %v, want room-1 and room-2", rooms)
+	}
+
+	if len(hub.rooms) != 0 {
+		t.Fatalf("hub still tracks rooms %v", hub.rooms)
+	}
+
+	if hub.Join(client, "room-1") {
+		t.Fatal("unregistered client joined a room")
+	}
+}
+
+func TestHubDropsSlowClient(t *testing.T) {
+	hub := NewHub()
+
+	slow := newTestClient(t, hub, 1)
+	fast := newTestClient(t, hub, 4)
+
+	hub.Join(slow, "room")
+	hub.Join(fast, "room")
+
+	publishTestEvent(t, hub, "room", "event-1")
+	publishTestEvent(t, hub, "room", "event-2")
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("slow client was not closed")
+	}
+
+	if slow.closeCode != websocket.ClosePolicyViolation {
+		t.Fatalf("got close code %d, want %d", slow.closeCode, websocket.ClosePolicyViolation)
+	}
+
+	if ids := queuedEventIDs(t, fast); len(ids) != 2 {
+		t.Fatalf("fast client got %v, want both events", ids)
+	}
+}
+
+func TestHubShutdownDrainsClients(t *testing.T) {
+	hub := NewHub()
+	client := newTestClient(t, hub, 1)
+
+	go func() {
+		<-client.Done()
+		hub.Unregister(client)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	if client.closeCode != websocket.CloseGoingAway {
+		t.Fatalf("got close code %d, want %d", client.closeCode, websocket.CloseGoingAway)
+	}
+
+	if err := hub.Register(NewClient(nil, &domain.Principal{}, ClientConfig{})); !errors.Is(err, ErrDraining) {
+		t.Fatalf("register after shutdown: got %v, want %v", err, ErrDraining)
+	}
+}
+
+func TestHubShutdownGivesUp(t *testing.T) {
+	hub := NewHub()
+	newTestClient(t, hub, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := hub.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
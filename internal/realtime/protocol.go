@@ -0,0 +1,55 @@
+package realtime
+
+import (
+	"github.com/Meystergod/gochat/internal/domain"
+)
+
+const (
+	CommandJoin  = "join"
+	CommandLeave = "leave"
+	CommandSend  = "send"
+	CommandPing  = "ping"
+)
+
+const (
+	CodeMalformedCommand = "command.malformed"
+	CodeUnknownCommand   = "command.unknown"
+)
+
+const (
+	FrameAck   = "ack"
+	FrameError = "error"
+	FrameEvent = "event"
+	FramePong  = "pong"
+)
+
+// Command is a frame sent by a client. ID is chosen by the client and echoed
+// back in the matching ack or error frame.
+type Command struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	RoomID string `json:"room_id,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Frame is a frame sent by the server.
+type Frame struct {
+	Type  string        `json:"type"`
+	ID    string        `json:"id,omitempty"`
+	Code  string        `json:"code,omitempty"`
+	Error string        `json:"error,omitempty"`
+	Event *domain.Event `json:"event,omitempty"`
+	Data  interface{}   `json:"data,omitempty"`
+}
+
+func NewAckFrame(id string, data interface{}) *Frame {
+	return &Frame{Type: FrameAck, ID: id, Data: data}
+}
+
+func NewErrorFrame(id, code, message string) *Frame {
+	return &Frame{Type: FrameError, ID: id, Code: code, Error: message}
+}
+
+func NewEventFrame(event *domain.Event) *Frame {
+	return &Frame{Type: FrameEvent, Event: event}
+}
@@ -0,0 +1,55 @@
+package realtime
+
+import (
+	"sync"
+
+	"github.com/Meystergod/gochat/internal/domain"
+)
+
+// Subscription is a buffered stream of events of a single room. It is closed
+// when its consumer falls behind or the hub shuts down.
+type Subscription struct {
+	roomID    string
+	events    chan *domain.Event
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSubscription(roomID string, buffer int) *Subscription {
+	return &Subscription{
+		roomID: roomID,
+		events: make(chan *domain.Event, buffer),
+		done:   make(chan struct{}),
+	}
+}
+
+func (s *Subscription) Events() <-chan *domain.Event {
+	return s.events
+}
+
+// Done is closed when the subscription stops receiving events.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Subscription) deliver(event *domain.Event, _ []byte) bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+
+	select {
+	case s.events <- event:
+		return true
+	default:
+		s.shutdown()
+		return false
+	}
+}
+
+func (s *Subscription) shutdown() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
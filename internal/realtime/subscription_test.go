@@ -0,0 +1,101 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionReceivesRoomEvents(t *testing.T) {
+	hub := NewHub()
+
+	subscription, err := hub.Subscribe("room-1", 4)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	publishTestEvent(t, hub, "room-2", "event-1")
+	publishTestEvent(t, hub, "room-1", "event-2")
+
+	select {
+	case event := <-subscription.Events():
+		if event.ID != "event-2" {
+			t.Fatalf("got event %s, want event-2", event.ID)
+		}
+	default:
+		t.Fatal("event of the subscribed room was not delivered")
+	}
+
+	if len(subscription.Events()) != 0 {
+		t.Fatal("event of another room was delivered")
+	}
+
+	hub.Unsubscribe(subscription)
+
+	select {
+	case <-subscription.Done():
+	default:
+		t.Fatal("unsubscribed subscription is not done")
+	}
+
+	publishTestEvent(t, hub, "room-1", "event-3")
+
+	if len(subscription.Events()) != 0 {
+		t.Fatal("event was delivered after unsubscribing")
+	}
+}
+
+func TestSubscriptionDroppedWhenFull(t *testing.T) {
+	hub := NewHub()
+
+	slow, err := hub.Subscribe("room", 1)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	publishTestEvent(t, hub, "room", "event-1")
+	publishTestEvent(t, hub, "room", "event-2")
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("full subscription was not closed")
+	}
+
+	// the stream handler still unsubscribes a dropped subscription
+	hub.Unsubscribe(slow)
+	hub.Unsubscribe(slow)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err = hub.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+}
+
+func TestSubscribeWhileDraining(t *testing.T) {
+	hub := NewHub()
+
+	subscription, err := hub.Subscribe("room", 1)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	go func() {
+		<-subscription.Done()
+		hub.Unsubscribe(subscription)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err = hub.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	if _, err = hub.Subscribe("room", 1); !errors.Is(err, ErrDraining) {
+		t.Fatalf("got %v, want %v", err, ErrDraining)
+	}
+}
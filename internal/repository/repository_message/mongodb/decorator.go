@@ -0,0 +1,38 @@
+package repository_message
+
+import (
+	"github.com/Meystergod/gochat/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func messageToDomain(m *Message) domain.Message {
+	return domain.Message{
+		ID:        m.ID.Hex(),
+		RoomID:    m.RoomID.Hex(),
+		AuthorID:  m.AuthorID.Hex(),
+		Body:      m.Body,
+		CreatedAt: m.CreatedAt,
+		EditedAt:  m.EditedAt,
+		DeletedAt: m.DeletedAt,
+	}
+}
+
+func messageToRepository(message *domain.Message) (Message, error) {
+	roomOID, err := primitive.ObjectIDFromHex(message.RoomID)
+	if err != nil {
+		return Message{}, err
+	}
+
+	authorOID, err := primitive.ObjectIDFromHex(message.AuthorID)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		RoomID:    roomOID,
+		AuthorID:  authorOID,
+		Body:      message.Body,
+		CreatedAt: message.CreatedAt,
+	}, nil
+}
@@ -0,0 +1,17 @@
+package repository_message
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Message struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	RoomID    primitive.ObjectID `bson:"room_id"`
+	AuthorID  primitive.ObjectID `bson:"author_id"`
+	Body      string             `bson:"body"`
+	CreatedAt time.Time          `bson:"created_at"`
+	EditedAt  *time.Time         `bson:"edited_at,omitempty"`
+	DeletedAt *time.Time         `bson:"deleted_at,omitempty"`
+}
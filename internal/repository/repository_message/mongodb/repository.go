@@ -0,0 +1,242 @@
+package repository_message
+
+import (
+	"context"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MessageRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMessageRepository(storage *mongo.Database, collection string) *MessageRepository {
+	return &MessageRepository{
+		collection: storage.Collection(collection),
+	}
+}
+
+func (messageRepository *MessageRepository) CreateMessage(ctx context.Context, domainMessage *domain.Message) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	repositoryMessage, err := messageToRepository(domainMessage)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert message model")
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorConvertModel, err.Error())
+	}
+
+	result, err := messageRepository.collection.InsertOne(ctx, repositoryMessage)
+	if err != nil {
+		err = errors.Wrap(err, "failed to create message")
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorCreateOne, err.Error())
+	}
+
+	oid, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		err = errors.New("failed to convert message id to oid")
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorConvert, err.Error())
+	}
+
+	return oid.Hex(), nil
+}
+
+func (messageRepository *MessageRepository) GetMessage(ctx context.Context, id string) (*domain.Message, error) {
+	var repositoryMessage *Message
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert message id to oid")
+		return nil, apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+	}
+
+	filter := bson.M{"_id": oid}
+
+	result := messageRepository.collection.FindOne(ctx, filter)
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, apperror.NewAppError(apperror.ErrorMessageNotFound, "message with this id does not exist")
+	}
+	if result.Err() != nil {
+		err = errors.Wrap(result.Err(), "failed to get message")
+		return nil, apperror.NewAppError(apperror.ErrorGetOne, err.Error())
+	}
+
+	if err = result.Decode(&repositoryMessage); err != nil {
+		err = errors.Wrap(err, "failed to decode message mongo object to struct")
+		return nil, apperror.NewAppError(apperror.ErrorDecode, err.Error())
+	}
+
+	domainMessage := messageToDomain(repositoryMessage)
+
+	return &domainMessage, nil
+}
+
+// GetMessages returns one page of room history in chronological order. Pages
+// are keyed by ObjectID, which is monotonic per insert, so they stay stable
+// while new messages arrive. Without a cursor the newest page is returned.
+func (messageRepository *MessageRepository) GetMessages(ctx context.Context, messageCursor *domain.MessageCursor) (*domain.MessagePage, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	roomOID, err := primitive.ObjectIDFromHex(messageCursor.RoomID)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert room id to oid")
+		return nil, apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+	}
+
+	filter := bson.M{"room_id": roomOID}
+	sortDirection := -1
+
+	switch {
+	case messageCursor.After != "":
+		afterOID, err := primitive.ObjectIDFromHex(messageCursor.After)
+		if err != nil {
+			err = errors.Wrap(err, "failed to convert after cursor to oid")
+			return nil, apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+		}
+		filter["_id"] = bson.M{"$gt": afterOID}
+		sortDirection = 1
+	case messageCursor.Before != "":
+		beforeOID, err := primitive.ObjectIDFromHex(messageCursor.Before)
+		if err != nil {
+			err = errors.Wrap(err, "failed to convert before cursor to oid")
+			return nil, apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+		}
+		filter["_id"] = bson.M{"$lt": beforeOID}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "room_id", Value: 1}, {Key: "_id", Value: sortDirection}}).
+		SetLimit(int64(messageCursor.Limit + 1)).
+		SetBatchSize(int32(messageCursor.Limit + 1))
+
+	cursor, err := messageRepository.collection.Find(ctx, filter, opts)
+	if err != nil {
+		err = errors.Wrap(err, "failed to get messages")
+		return nil, apperror.NewAppError(apperror.ErrorGetAll, err.Error())
+	}
+
+	defer cursor.Close(ctx)
+
+	domainMessages := make([]domain.Message, 0, messageCursor.Limit)
+	hasMore := false
+
+	for cursor.Next(ctx) {
+		if len(domainMessages) == messageCursor.Limit {
+			hasMore = true
+			break
+		}
+
+		var repositoryMessage Message
+		if err = cursor.Decode(&repositoryMessage); err != nil {
+			err = errors.Wrap(err, "failed to decode message mongo object to struct")
+			return nil, apperror.NewAppError(apperror.ErrorDecode, err.Error())
+		}
+
+		domainMessages = append(domainMessages, messageToDomain(&repositoryMessage))
+	}
+
+	if err = cursor.Err(); err != nil {
+		err = errors.Wrap(err, "failed to iterate messages")
+		return nil, apperror.NewAppError(apperror.ErrorGetAll, err.Error())
+	}
+
+	if sortDirection < 0 {
+		for i, j := 0, len(domainMessages)-1; i < j; i, j = i+1, j-1 {
+			domainMessages[i], domainMessages[j] = domainMessages[j], domainMessages[i]
+		}
+	}
+
+	page := &domain.MessagePage{
+		Messages: domainMessages,
+		HasMore:  hasMore,
+	}
+
+	if len(domainMessages) > 0 {
+		page.NextBefore = domainMessages[0].ID
+		page.NextAfter = domainMessages[len(domainMessages)-1].ID
+	}
+
+	return page, nil
+}
+
+func (messageRepository *MessageRepository) EditMessage(ctx context.Context, id, body string, editedAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{"body": body, "edited_at": editedAt},
+	}
+
+	return messageRepository.updateMessage(ctx, id, update)
+}
+
+func (messageRepository *MessageRepository) DeleteMessage(ctx context.Context, id string, deletedAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{"body": utils.EmptyString, "deleted_at": deletedAt},
+	}
+
+	return messageRepository.updateMessage(ctx, id, update)
+}
+
+// DeleteRoomMessages permanently removes every message of a room, including
+// soft deleted ones.
+func (messageRepository *MessageRepository) DeleteRoomMessages(ctx context.Context, roomID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+
+	defer cancel()
+
+	roomOID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert room id to oid")
+		return apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+	}
+
+	if _, err = messageRepository.collection.DeleteMany(ctx, bson.M{"room_id": roomOID}); err != nil {
+		err = errors.Wrap(err, "failed to delete room messages")
+		return apperror.NewAppError(apperror.ErrorDeleteOne, err.Error())
+	}
+
+	return nil
+}
+
+func (messageRepository *MessageRepository) updateMessage(ctx context.Context, id string, update bson.M) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert message id to oid")
+		return apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+	}
+
+	filter := bson.M{
+		"_id":        oid,
+		"deleted_at": bson.M{"$exists": false},
+	}
+
+	result, err := messageRepository.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		err = errors.Wrap(err, "failed to update message")
+		return apperror.NewAppError(apperror.ErrorUpdateOne, err.Error())
+	}
+
+	if result.MatchedCount == 0 {
+		return apperror.NewAppError(apperror.ErrorMessageNotFound, "message with this id does not exist")
+	}
+
+	return nil
+}
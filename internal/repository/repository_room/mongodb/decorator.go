@@ -0,0 +1,50 @@
+package repository_room
+
+import (
+	"github.com/Meystergod/gochat/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func roomToDomain(r *Room) domain.Room {
+	members := make([]string, 0, len(r.Members))
+	for _, member := range r.Members {
+		members = append(members, member.Hex())
+	}
+
+	return domain.Room{
+		ID:         r.ID.Hex(),
+		Name:       r.Name,
+		Topic:      r.Topic,
+		Type:       r.Type,
+		OwnerID:    r.OwnerID.Hex(),
+		Members:    members,
+		ArchivedAt: r.ArchivedAt,
+		CreatedAt:  r.CreatedAt,
+	}
+}
+
+func roomToRepository(room *domain.Room) (Room, error) {
+	ownerOID, err := primitive.ObjectIDFromHex(room.OwnerID)
+	if err != nil {
+		return Room{}, err
+	}
+
+	members := make([]primitive.ObjectID, 0, len(room.Members))
+	for _, member := range room.Members {
+		memberOID, err := primitive.ObjectIDFromHex(member)
+		if err != nil {
+			return Room{}, err
+		}
+		members = append(members, memberOID)
+	}
+
+	return Room{
+		Name:      room.Name,
+		Topic:     room.Topic,
+		Type:      room.Type,
+		OwnerID:   ownerOID,
+		Members:   members,
+		CreatedAt: room.CreatedAt,
+	}, nil
+}
@@ -0,0 +1,18 @@
+package repository_room
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Room struct {
+	ID         primitive.ObjectID   `bson:"_id,omitempty"`
+	Name       string               `bson:"name"`
+	Topic      string               `bson:"topic"`
+	Type       string               `bson:"type"`
+	OwnerID    primitive.ObjectID   `bson:"owner_id"`
+	Members    []primitive.ObjectID `bson:"members"`
+	ArchivedAt *time.Time           `bson:"archived_at,omitempty"`
+	CreatedAt  time.Time            `bson:"created_at"`
+}
@@ -0,0 +1,202 @@
+package repository_room
+
+import (
+	"context"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RoomRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRoomRepository(storage *mongo.Database, collection string) *RoomRepository {
+	return &RoomRepository{
+		collection: storage.Collection(collection),
+	}
+}
+
+func (roomRepository *RoomRepository) CreateRoom(ctx context.Context, domainRoom *domain.Room) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	repositoryRoom, err := roomToRepository(domainRoom)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert room model")
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorConvertModel, err.Error())
+	}
+
+	result, err := roomRepository.collection.InsertOne(ctx, repositoryRoom)
+	if err != nil {
+		err = errors.Wrap(err, "failed to create room")
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorCreateOne, err.Error())
+	}
+
+	oid, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		err = errors.New("failed to convert room id to oid")
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorConvert, err.Error())
+	}
+
+	return oid.Hex(), nil
+}
+
+func (roomRepository *RoomRepository) GetRoom(ctx context.Context, id string) (*domain.Room, error) {
+	var repositoryRoom *Room
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert room id to oid")
+		return nil, apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+	}
+
+	filter := bson.M{"_id": oid}
+
+	result := roomRepository.collection.FindOne(ctx, filter)
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, apperror.NewAppError(apperror.ErrorRoomNotFound, "room with this id does not exist")
+	}
+	if result.Err() != nil {
+		err = errors.Wrap(result.Err(), "failed to get room")
+		return nil, apperror.NewAppError(apperror.ErrorGetOne, err.Error())
+	}
+
+	if err = result.Decode(&repositoryRoom); err != nil {
+		err = errors.Wrap(err, "failed to decode room mongo object to struct")
+		return nil, apperror.NewAppError(apperror.ErrorDecode, err.Error())
+	}
+
+	domainRoom := roomToDomain(repositoryRoom)
+
+	return &domainRoom, nil
+}
+
+func (roomRepository *RoomRepository) GetRooms(ctx context.Context, roomFilter *domain.RoomFilter) ([]domain.Room, error) {
+	var repositoryRooms []Room
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	filter := bson.M{}
+
+	if roomFilter.MemberID != "" {
+		memberOID, err := primitive.ObjectIDFromHex(roomFilter.MemberID)
+		if err != nil {
+			err = errors.Wrap(err, "failed to convert member id to oid")
+			return nil, apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+		}
+
+		filter["$or"] = bson.A{
+			bson.M{"type": domain.RoomTypePublic},
+			bson.M{"members": memberOID},
+		}
+	}
+
+	if !roomFilter.IncludeArchived {
+		filter["archived_at"] = bson.M{"$exists": false}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := roomRepository.collection.Find(ctx, filter, opts)
+	if err != nil {
+		err = errors.Wrap(err, "failed to get rooms")
+		return nil, apperror.NewAppError(apperror.ErrorGetAll, err.Error())
+	}
+
+	if err = cursor.All(ctx, &repositoryRooms); err != nil {
+		err = errors.Wrap(err, "failed to decode rooms mongo objects to struct")
+		return nil, apperror.NewAppError(apperror.ErrorDecode, err.Error())
+	}
+
+	domainRooms := make([]domain.Room, 0, len(repositoryRooms))
+
+	for i := range repositoryRooms {
+		domainRooms = append(domainRooms, roomToDomain(&repositoryRooms[i]))
+	}
+
+	return domainRooms, nil
+}
+
+func (roomRepository *RoomRepository) RenameRoom(ctx context.Context, id, name, topic string) error {
+	update := bson.M{
+		"$set": bson.M{"name": name, "topic": topic},
+	}
+
+	return roomRepository.updateRoom(ctx, id, update)
+}
+
+func (roomRepository *RoomRepository) ArchiveRoom(ctx context.Context, id string, archivedAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{"archived_at": archivedAt},
+	}
+
+	return roomRepository.updateRoom(ctx, id, update)
+}
+
+func (roomRepository *RoomRepository) DeleteRoom(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert room id to oid")
+		return apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+	}
+
+	filter := bson.M{"_id": oid}
+
+	result, err := roomRepository.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		err = errors.Wrap(err, "failed to delete room")
+		return apperror.NewAppError(apperror.ErrorDeleteOne, err.Error())
+	}
+
+	if result.DeletedCount == 0 {
+		return apperror.NewAppError(apperror.ErrorRoomNotFound, "room with this id does not exist")
+	}
+
+	return nil
+}
+
+func (roomRepository *RoomRepository) updateRoom(ctx context.Context, id string, update bson.M) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert room id to oid")
+		return apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+	}
+
+	filter := bson.M{"_id": oid}
+
+	result, err := roomRepository.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		err = errors.Wrap(err, "failed to update room")
+		return apperror.NewAppError(apperror.ErrorUpdateOne, err.Error())
+	}
+
+	if result.MatchedCount == 0 {
+		return apperror.NewAppError(apperror.ErrorRoomNotFound, "room with this id does not exist")
+	}
+
+	return nil
+}
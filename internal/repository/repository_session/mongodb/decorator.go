@@ -0,0 +1,35 @@
+package repository_session
+
+import (
+	"github.com/Meystergod/gochat/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func sessionToDomain(s *Session) domain.Session {
+	return domain.Session{
+		ID:        s.ID.Hex(),
+		UserID:    s.UserID.Hex(),
+		FamilyID:  s.FamilyID,
+		TokenHash: s.TokenHash,
+		CreatedAt: s.CreatedAt,
+		ExpiresAt: s.ExpiresAt,
+		UsedAt:    s.UsedAt,
+		RevokedAt: s.RevokedAt,
+	}
+}
+
+func sessionToRepository(session *domain.Session) (Session, error) {
+	userOID, err := primitive.ObjectIDFromHex(session.UserID)
+	if err != nil {
+		return Session{}, err
+	}
+
+	return Session{
+		UserID:    userOID,
+		FamilyID:  session.FamilyID,
+		TokenHash: session.TokenHash,
+		CreatedAt: session.CreatedAt,
+		ExpiresAt: session.ExpiresAt,
+	}, nil
+}
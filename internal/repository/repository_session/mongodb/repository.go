@@ -0,0 +1,157 @@
+package repository_session
+
+import (
+	"context"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SessionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSessionRepository(storage *mongo.Database, collection string) *SessionRepository {
+	return &SessionRepository{
+		collection: storage.Collection(collection),
+	}
+}
+
+func (sessionRepository *SessionRepository) CreateSession(ctx context.Context, domainSession *domain.Session) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	repositorySession, err := sessionToRepository(domainSession)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert session model")
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorConvertModel, err.Error())
+	}
+
+	result, err := sessionRepository.collection.InsertOne(ctx, repositorySession)
+	if err != nil {
+		err = errors.Wrap(err, "failed to create session")
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorCreateOne, err.Error())
+	}
+
+	oid, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		err = errors.New("failed to convert session id to oid")
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorConvert, err.Error())
+	}
+
+	return oid.Hex(), nil
+}
+
+func (sessionRepository *SessionRepository) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*domain.Session, error) {
+	var repositorySession *Session
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	filter := bson.M{"token_hash": tokenHash}
+
+	result := sessionRepository.collection.FindOne(ctx, filter)
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, apperror.NewAppError(apperror.ErrorNotFound, "session does not exist")
+	}
+	if result.Err() != nil {
+		err := errors.Wrap(result.Err(), "failed to get session")
+		return nil, apperror.NewAppError(apperror.ErrorGetOne, err.Error())
+	}
+
+	if err := result.Decode(&repositorySession); err != nil {
+		err = errors.Wrap(err, "failed to decode session mongo object to struct")
+		return nil, apperror.NewAppError(apperror.ErrorDecode, err.Error())
+	}
+
+	domainSession := sessionToDomain(repositorySession)
+
+	return &domainSession, nil
+}
+
+// UseSession atomically marks an active session as used. It returns false when
+// the session was already used or revoked, which signals refresh token reuse.
+func (sessionRepository *SessionRepository) UseSession(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert session id to oid")
+		return false, apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+	}
+
+	filter := bson.M{
+		"_id":        oid,
+		"used_at":    bson.M{"$exists": false},
+		"revoked_at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{"used_at": time.Now()},
+	}
+
+	result, err := sessionRepository.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		err = errors.Wrap(err, "failed to mark session as used")
+		return false, apperror.NewAppError(apperror.ErrorUpdateOne, err.Error())
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (sessionRepository *SessionRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	filter := bson.M{
+		"family_id":  familyID,
+		"revoked_at": bson.M{"$exists": false},
+	}
+
+	return sessionRepository.revoke(ctx, filter)
+}
+
+// RevokeUserSessions revokes every active session of the user, logging the
+// user out of all devices once the refresh tokens are next presented.
+func (sessionRepository *SessionRepository) RevokeUserSessions(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert user id to oid")
+		return apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+	}
+
+	filter := bson.M{
+		"user_id":    oid,
+		"revoked_at": bson.M{"$exists": false},
+	}
+
+	return sessionRepository.revoke(ctx, filter)
+}
+
+func (sessionRepository *SessionRepository) revoke(ctx context.Context, filter bson.M) error {
+	update := bson.M{
+		"$set": bson.M{"revoked_at": time.Now()},
+	}
+
+	if _, err := sessionRepository.collection.UpdateMany(ctx, filter, update); err != nil {
+		err = errors.Wrap(err, "failed to revoke sessions")
+		return apperror.NewAppError(apperror.ErrorUpdateOne, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package repository_token
+
+import (
+	"github.com/Meystergod/gochat/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func userTokenToDomain(t *UserToken) domain.UserToken {
+	return domain.UserToken{
+		ID:        t.ID.Hex(),
+		UserID:    t.UserID.Hex(),
+		Purpose:   t.Purpose,
+		TokenHash: t.TokenHash,
+		CreatedAt: t.CreatedAt,
+		ExpiresAt: t.ExpiresAt,
+		UsedAt:    t.UsedAt,
+	}
+}
+
+func userTokenToRepository(token *domain.UserToken) (UserToken, error) {
+	userOID, err := primitive.ObjectIDFromHex(token.UserID)
+	if err != nil {
+		return UserToken{}, err
+	}
+
+	return UserToken{
+		UserID:    userOID,
+		Purpose:   token.Purpose,
+		TokenHash: token.TokenHash,
+		CreatedAt: token.CreatedAt,
+		ExpiresAt: token.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,114 @@
+package repository_token
+
+import (
+	"context"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UserTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewUserTokenRepository(storage *mongo.Database, collection string) *UserTokenRepository {
+	return &UserTokenRepository{
+		collection: storage.Collection(collection),
+	}
+}
+
+func (userTokenRepository *UserTokenRepository) CreateUserToken(ctx context.Context, domainToken *domain.UserToken) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	repositoryToken, err := userTokenToRepository(domainToken)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert user token model")
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorConvertModel, err.Error())
+	}
+
+	result, err := userTokenRepository.collection.InsertOne(ctx, repositoryToken)
+	if err != nil {
+		err = errors.Wrap(err, "failed to create user token")
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorCreateOne, err.Error())
+	}
+
+	oid, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		err = errors.New("failed to convert user token id to oid")
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorConvert, err.Error())
+	}
+
+	return oid.Hex(), nil
+}
+
+// UseUserToken atomically consumes an unused, unexpired token of the given
+// purpose, so that a token can never be redeemed twice.
+func (userTokenRepository *UserTokenRepository) UseUserToken(ctx context.Context, purpose, tokenHash string, usedAt time.Time) (*domain.UserToken, error) {
+	var repositoryToken *UserToken
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"purpose":    purpose,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": usedAt},
+	}
+	update := bson.M{
+		"$set": bson.M{"used_at": usedAt},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	result := userTokenRepository.collection.FindOneAndUpdate(ctx, filter, update, opts)
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, apperror.NewAppError(apperror.ErrorNotFound, "user token does not exist, expired or was already used")
+	}
+	if result.Err() != nil {
+		err := errors.Wrap(result.Err(), "failed to use user token")
+		return nil, apperror.NewAppError(apperror.ErrorUpdateOne, err.Error())
+	}
+
+	if err := result.Decode(&repositoryToken); err != nil {
+		err = errors.Wrap(err, "failed to decode user token mongo object to struct")
+		return nil, apperror.NewAppError(apperror.ErrorDecode, err.Error())
+	}
+
+	domainToken := userTokenToDomain(repositoryToken)
+
+	return &domainToken, nil
+}
+
+// DeleteUserTokens removes the outstanding tokens of the given purpose issued
+// to the user, so that only the most recently mailed token stays valid.
+func (userTokenRepository *UserTokenRepository) DeleteUserTokens(ctx context.Context, userID, purpose string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert user id to oid")
+		return apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+	}
+
+	filter := bson.M{"user_id": oid, "purpose": purpose}
+
+	if _, err = userTokenRepository.collection.DeleteMany(ctx, filter); err != nil {
+		err = errors.Wrap(err, "failed to delete user tokens")
+		return apperror.NewAppError(apperror.ErrorDeleteOne, err.Error())
+	}
+
+	return nil
+}
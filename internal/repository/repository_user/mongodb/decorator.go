@@ -1,9 +1,15 @@
 package repository_user
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"time"
+
 	"github.com/Meystergod/gochat/internal/domain"
 
 	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -16,10 +22,22 @@ func userToDomain(u *User) domain.User {
 		Name:         u.Name,
 		Email:        u.Email,
 		Password:     u.Password,
+		Role:         roleOrDefault(u.Role),
 		RegisteredAt: u.RegisteredAt,
+		Version:      u.Version,
+		VerifiedAt:   u.VerifiedAt,
+		DeletedAt:    u.DeletedAt,
 	}
 }
 
+func roleOrDefault(role string) string {
+	if role == "" {
+		return domain.RoleUser
+	}
+
+	return role
+}
+
 func userToRepository(user *domain.User, method string) (User, error) {
 	switch method {
 	case MethodCreate:
@@ -27,7 +45,9 @@ func userToRepository(user *domain.User, method string) (User, error) {
 			Name:         user.Name,
 			Email:        user.Email,
 			Password:     user.Password,
+			Role:         user.Role,
 			RegisteredAt: user.RegisteredAt,
+			Version:      1,
 		}, nil
 	case MethodUpdate:
 		oid, err := primitive.ObjectIDFromHex(user.ID)
@@ -44,3 +64,194 @@ func userToRepository(user *domain.User, method string) (User, error) {
 		return User{}, errors.New("unknown method for convert to repository model")
 	}
 }
+
+func userPatchToRepository(patch *domain.UserPatch) bson.M {
+	fields := bson.M{}
+
+	if patch.Name != nil {
+		fields["name"] = *patch.Name
+	}
+	if patch.Email != nil {
+		fields["email"] = *patch.Email
+	}
+	if patch.Password != nil {
+		fields["password"] = *patch.Password
+	}
+
+	return fields
+}
+
+// userUpdatePipeline builds the update that sets fields and bumps the version.
+// A changed email removes verified_at, because the new address has not been
+// confirmed yet. The values are wrapped in $literal so that strings starting
+// with a dollar, such as bcrypt hashes, are not read as field paths.
+func userUpdatePipeline(fields bson.M) bson.A {
+	set := bson.M{"version": bson.M{"$add": bson.A{"$version", 1}}}
+	for key, value := range fields {
+		set[key] = bson.M{"$literal": value}
+	}
+
+	pipeline := bson.A{}
+
+	if email, ok := fields["email"]; ok {
+		pipeline = append(pipeline, bson.M{"$set": bson.M{
+			"verified_at": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$email", bson.M{"$literal": email}}},
+				"$verified_at",
+				"$$REMOVE",
+			}},
+		}})
+	}
+
+	return append(pipeline, bson.M{"$set": set})
+}
+
+// notDeleted narrows filter to accounts that have not been soft deleted. Every
+// query except restore and purge goes through it. It matches the filter of the
+// email index, so that lookups by email can use the index.
+func notDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$type": "null"}
+
+	return filter
+}
+
+func versionFilter(filter bson.M, ifMatch []int64) bson.M {
+	if len(ifMatch) > 0 {
+		filter["version"] = bson.M{"$in": ifMatch}
+	}
+
+	return filter
+}
+
+var userSortFields = map[string]string{
+	domain.UserSortID:           "_id",
+	domain.UserSortName:         "name",
+	domain.UserSortEmail:        "email",
+	domain.UserSortRegisteredAt: "registered_at",
+}
+
+// userCursor is the decoded form of the opaque cursor handed out to clients.
+// It remembers the sort and direction it was produced for together with the
+// sort key and id of the last returned user, which is enough to resume a
+// keyset scan.
+type userCursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	Value      string `json:"v,omitempty"`
+	ID         string `json:"id"`
+}
+
+func userQueryToFilter(query *domain.UserQuery) bson.M {
+	filter := notDeleted(bson.M{})
+
+	if query.NamePrefix != "" {
+		filter["name"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(query.NamePrefix), Options: "i"}
+	}
+
+	if query.EmailDomain != "" {
+		filter["email"] = primitive.Regex{Pattern: "@" + regexp.QuoteMeta(query.EmailDomain) + "$", Options: "i"}
+	}
+
+	registeredAt := bson.M{}
+	if query.RegisteredFrom != nil {
+		registeredAt["$gte"] = *query.RegisteredFrom
+	}
+	if query.RegisteredTo != nil {
+		registeredAt["$lte"] = *query.RegisteredTo
+	}
+	if len(registeredAt) > 0 {
+		filter["registered_at"] = registeredAt
+	}
+
+	return filter
+}
+
+func userQueryToSort(query *domain.UserQuery) bson.D {
+	direction := 1
+	if query.Descending {
+		direction = -1
+	}
+
+	field := userSortFields[query.Sort]
+	if field == "" || field == "_id" {
+		return bson.D{{Key: "_id", Value: direction}}
+	}
+
+	return bson.D{{Key: field, Value: direction}, {Key: "_id", Value: direction}}
+}
+
+func encodeUserCursor(query *domain.UserQuery, u *User) (string, error) {
+	c := userCursor{
+		Sort:       query.Sort,
+		Descending: query.Descending,
+		ID:         u.ID.Hex(),
+	}
+
+	switch query.Sort {
+	case domain.UserSortName:
+		c.Value = u.Name
+	case domain.UserSortEmail:
+		c.Value = u.Email
+	case domain.UserSortRegisteredAt:
+		c.Value = u.RegisteredAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// userCursorToFilter decodes the cursor of the query and returns the condition
+// selecting the users that follow it in the requested order.
+func userCursorToFilter(query *domain.UserQuery) (bson.M, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding cursor")
+	}
+
+	var c userCursor
+	if err = json.Unmarshal(raw, &c); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling cursor")
+	}
+
+	if c.Sort != query.Sort {
+		return nil, errors.New("cursor does not match the requested sort")
+	}
+
+	if c.Descending != query.Descending {
+		return nil, errors.New("cursor does not match the requested order")
+	}
+
+	oid, err := primitive.ObjectIDFromHex(c.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "converting cursor id to oid")
+	}
+
+	operator := "$gt"
+	if query.Descending {
+		operator = "$lt"
+	}
+
+	field := userSortFields[query.Sort]
+	if field == "" || field == "_id" {
+		return bson.M{"_id": bson.M{operator: oid}}, nil
+	}
+
+	var value interface{} = c.Value
+	if query.Sort == domain.UserSortRegisteredAt {
+		value, err = time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing cursor time")
+		}
+	}
+
+	return bson.M{
+		"$or": bson.A{
+			bson.M{field: bson.M{operator: value}},
+			bson.M{field: value, "_id": bson.M{operator: oid}},
+		},
+	}, nil
+}
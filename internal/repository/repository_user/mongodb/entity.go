@@ -11,5 +11,11 @@ type User struct {
 	Name         string             `bson:"name"`
 	Email        string             `bson:"email"`
 	Password     string             `bson:"password"`
+	Role         string             `bson:"role,omitempty"`
 	RegisteredAt time.Time          `bson:"registered_at,omitempty"`
+	Version      int64              `bson:"version,omitempty"`
+	VerifiedAt   *time.Time         `bson:"verified_at,omitempty"`
+	// DeletedAt is stored as null for live accounts, which is what the partial
+	// unique email index filters on.
+	DeletedAt *time.Time `bson:"deleted_at"`
 }
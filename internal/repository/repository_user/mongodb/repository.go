@@ -12,8 +12,20 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("github.com/Meystergod/gochat/internal/repository/repository_user")
+
+// emailCollation compares emails case-insensitively. Queries by email must use
+// it as well, otherwise they can not be served by the unique email index that
+// the create_user_indexes migration builds with the same collation.
+var emailCollation = &options.Collation{
+	Locale:   "en",
+	Strength: 2,
+}
+
 type UserRepository struct {
 	collection *mongo.Collection
 }
@@ -25,6 +37,9 @@ func NewUserRepository(storage *mongo.Database, collection string) *UserReposito
 }
 
 func (userRepository *UserRepository) CreateUser(ctx context.Context, domainUser *domain.User) (string, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.CreateUser")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 
 	defer cancel()
@@ -36,6 +51,9 @@ func (userRepository *UserRepository) CreateUser(ctx context.Context, domainUser
 	}
 
 	result, err := userRepository.collection.InsertOne(ctx, repositoryUser)
+	if mongo.IsDuplicateKeyError(err) {
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorUserEmailTaken, "user with this email already exists")
+	}
 	if err != nil {
 		err = errors.Wrap(err, "failed to create user")
 		return utils.EmptyString, apperror.NewAppError(apperror.ErrorCreateOne, err.Error())
@@ -51,6 +69,9 @@ func (userRepository *UserRepository) CreateUser(ctx context.Context, domainUser
 }
 
 func (userRepository *UserRepository) GetUser(ctx context.Context, id string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.GetUser")
+	defer span.End()
+
 	var repositoryUser *User
 
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -60,12 +81,15 @@ func (userRepository *UserRepository) GetUser(ctx context.Context, id string) (*
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		err = errors.Wrap(err, "failed to convert user id to oid")
-		return nil, apperror.NewAppError(apperror.ErrorConvert, err.Error())
+		return nil, apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
 	}
 
-	filter := bson.M{"_id": oid}
+	filter := notDeleted(bson.M{"_id": oid})
 
 	result := userRepository.collection.FindOne(ctx, filter)
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, apperror.NewAppError(apperror.ErrorUserNotFound, "user with this id does not exist")
+	}
 	if result.Err() != nil {
 		err = errors.Wrap(result.Err(), "failed to get user")
 		return nil, apperror.NewAppError(apperror.ErrorGetOne, err.Error())
@@ -81,32 +105,127 @@ func (userRepository *UserRepository) GetUser(ctx context.Context, id string) (*
 	return &domainUser, nil
 }
 
-func (userRepository *UserRepository) GetAllUsers(ctx context.Context) (*[]domain.User, error) {
-	var repositoryUsers []User
+func (userRepository *UserRepository) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.GetUserByEmail")
+	defer span.End()
+
+	var repositoryUser *User
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	filter := notDeleted(bson.M{"email": email})
+	opts := options.FindOne().SetCollation(emailCollation)
+
+	result := userRepository.collection.FindOne(ctx, filter, opts)
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, apperror.NewAppError(apperror.ErrorUserNotFound, "user with this email does not exist")
+	}
+	if result.Err() != nil {
+		err := errors.Wrap(result.Err(), "failed to get user by email")
+		return nil, apperror.NewAppError(apperror.ErrorGetOne, err.Error())
+	}
+
+	if err := result.Decode(&repositoryUser); err != nil {
+		err = errors.Wrap(err, "failed to decode user mongo object to struct")
+		return nil, apperror.NewAppError(apperror.ErrorDecode, err.Error())
+	}
+
+	domainUser := userToDomain(repositoryUser)
+
+	return &domainUser, nil
+}
+
+func (userRepository *UserRepository) GetUsers(ctx context.Context, query *domain.UserQuery) (*domain.UserPage, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.GetUsers")
+	defer span.End()
 
-	filter := bson.M{}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
 
-	cursor, err := userRepository.collection.Find(ctx, filter)
+	filter := userQueryToFilter(query)
+
+	total, err := userRepository.collection.CountDocuments(ctx, filter)
 	if err != nil {
-		err = errors.Wrap(err, "failed to get all users")
+		err = errors.Wrap(err, "failed to count users")
 		return nil, apperror.NewAppError(apperror.ErrorGetAll, err.Error())
 	}
 
-	if err = cursor.All(ctx, &repositoryUsers); err != nil {
-		err = errors.Wrap(err, "failed to decode all users mongo objects to struct")
-		return nil, apperror.NewAppError(apperror.ErrorDecode, err.Error())
+	opts := options.Find().
+		SetSort(userQueryToSort(query)).
+		SetLimit(int64(query.Limit + 1))
+
+	pageFilter := filter
+
+	if query.Cursor != "" {
+		cursorFilter, err := userCursorToFilter(query)
+		if err != nil {
+			err = errors.Wrap(err, "failed to decode users cursor")
+			return nil, apperror.NewAppError(apperror.ErrorGetUrlParams, err.Error())
+		}
+		pageFilter = bson.M{"$and": bson.A{filter, cursorFilter}}
+	} else if query.Page > 1 {
+		opts.SetSkip(int64((query.Page - 1) * query.Limit))
+	}
+
+	cursor, err := userRepository.collection.Find(ctx, pageFilter, opts)
+	if err != nil {
+		err = errors.Wrap(err, "failed to get users")
+		return nil, apperror.NewAppError(apperror.ErrorGetAll, err.Error())
+	}
+
+	defer cursor.Close(ctx)
+
+	domainUsers := make([]domain.User, 0, query.Limit)
+	hasMore := false
+
+	var lastUser User
+
+	for cursor.Next(ctx) {
+		if len(domainUsers) == query.Limit {
+			hasMore = true
+			break
+		}
+
+		var repositoryUser User
+		if err = cursor.Decode(&repositoryUser); err != nil {
+			err = errors.Wrap(err, "failed to decode user mongo object to struct")
+			return nil, apperror.NewAppError(apperror.ErrorDecode, err.Error())
+		}
+
+		domainUsers = append(domainUsers, userToDomain(&repositoryUser))
+		lastUser = repositoryUser
 	}
 
-	var domainUsers []domain.User
+	if err = cursor.Err(); err != nil {
+		err = errors.Wrap(err, "failed to iterate users")
+		return nil, apperror.NewAppError(apperror.ErrorGetAll, err.Error())
+	}
 
-	for _, u := range repositoryUsers {
-		domainUsers = append(domainUsers, userToDomain(&u))
+	page := &domain.UserPage{
+		Users: domainUsers,
+		Total: total,
+		Limit: query.Limit,
+		Page:  query.Page,
 	}
 
-	return &domainUsers, nil
+	if hasMore {
+		page.NextCursor, err = encodeUserCursor(query, &lastUser)
+		if err != nil {
+			err = errors.Wrap(err, "failed to encode users cursor")
+			return nil, apperror.NewAppError(apperror.ErrorConvert, err.Error())
+		}
+	}
+
+	return page, nil
 }
 
-func (userRepository *UserRepository) UpdateUser(ctx context.Context, domainUser *domain.User) error {
+func (userRepository *UserRepository) UpdateUser(ctx context.Context, domainUser *domain.User, ifMatch []int64) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.UpdateUser")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 
 	defer cancel()
@@ -114,13 +233,13 @@ func (userRepository *UserRepository) UpdateUser(ctx context.Context, domainUser
 	repositoryUser, err := userToRepository(domainUser, MethodUpdate)
 	if err != nil {
 		err = errors.Wrap(err, "failed to convert user model")
-		return apperror.NewAppError(apperror.ErrorConvertModel, err.Error())
+		return nil, apperror.NewAppError(apperror.ErrorConvertModel, err.Error())
 	}
 
 	userByte, err := bson.Marshal(&repositoryUser)
 	if err != nil {
 		err = errors.Wrap(err, "failed to marshal user model to bytes")
-		return apperror.NewAppError(apperror.ErrorDecode, err.Error())
+		return nil, apperror.NewAppError(apperror.ErrorDecode, err.Error())
 	}
 
 	var object bson.M
@@ -128,32 +247,114 @@ func (userRepository *UserRepository) UpdateUser(ctx context.Context, domainUser
 	err = bson.Unmarshal(userByte, &object)
 	if err != nil {
 		err = errors.Wrap(err, "failed to unmarshal bytes to bson")
-		return apperror.NewAppError(apperror.ErrorDecode, err.Error())
+		return nil, apperror.NewAppError(apperror.ErrorDecode, err.Error())
 	}
 
 	delete(object, "_id")
+	delete(object, "version")
 
-	update := bson.M{
-		"$set": object,
+	return userRepository.findOneAndUpdate(ctx, repositoryUser.ID, ifMatch, userUpdatePipeline(object))
+}
+
+func (userRepository *UserRepository) PatchUser(ctx context.Context, id string, patch *domain.UserPatch, ifMatch []int64) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.PatchUser")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert user id to oid")
+		return nil, apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
 	}
 
-	filter := bson.M{"_id": repositoryUser.ID}
+	return userRepository.findOneAndUpdate(ctx, oid, ifMatch, userUpdatePipeline(userPatchToRepository(patch)))
+}
+
+// MarkUserVerified records that the user confirmed the email address. Marking
+// an already verified user keeps the original verification time.
+func (userRepository *UserRepository) MarkUserVerified(ctx context.Context, id string, verifiedAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "UserRepository.MarkUserVerified")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert user id to oid")
+		return apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+	}
+
+	filter := notDeleted(bson.M{"_id": oid, "verified_at": bson.M{"$exists": false}})
+	update := bson.M{
+		"$set": bson.M{"verified_at": verifiedAt},
+		"$inc": bson.M{"version": 1},
+	}
 
 	result, err := userRepository.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		err = errors.Wrap(err, "failed to update user")
+		err = errors.Wrap(err, "failed to mark user verified")
 		return apperror.NewAppError(apperror.ErrorUpdateOne, err.Error())
 	}
 
 	if result.MatchedCount == 0 {
-		err = errors.New("can not be matched: failed to get user in database for update")
+		count, err := userRepository.collection.CountDocuments(ctx, notDeleted(bson.M{"_id": oid}))
+		if err != nil {
+			err = errors.Wrap(err, "failed to check user existence")
+			return apperror.NewAppError(apperror.ErrorGetOne, err.Error())
+		}
+
+		if count == 0 {
+			return apperror.NewAppError(apperror.ErrorUserNotFound, "user with this id does not exist")
+		}
+	}
+
+	return nil
+}
+
+func (userRepository *UserRepository) UpdateUserPassword(ctx context.Context, id string, passwordHash string) error {
+	ctx, span := tracer.Start(ctx, "UserRepository.UpdateUserPassword")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert user id to oid")
+		return apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+	}
+
+	filter := notDeleted(bson.M{"_id": oid})
+	update := bson.M{
+		"$set": bson.M{"password": passwordHash},
+		"$inc": bson.M{"version": 1},
+	}
+
+	result, err := userRepository.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		err = errors.Wrap(err, "failed to update user password")
 		return apperror.NewAppError(apperror.ErrorUpdateOne, err.Error())
 	}
 
+	if result.MatchedCount == 0 {
+		return apperror.NewAppError(apperror.ErrorUserNotFound, "user with this id does not exist")
+	}
+
 	return nil
 }
 
-func (userRepository *UserRepository) DeleteUser(ctx context.Context, id string) error {
+// DeleteUser soft deletes the user. The account stays in the collection, hidden
+// from every other query, until it is restored or purged.
+func (userRepository *UserRepository) DeleteUser(ctx context.Context, id string, ifMatch []int64, deletedAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "UserRepository.DeleteUser")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 
 	defer cancel()
@@ -161,21 +362,141 @@ func (userRepository *UserRepository) DeleteUser(ctx context.Context, id string)
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		err = errors.Wrap(err, "failed to convert user id to oid")
-		return apperror.NewAppError(apperror.ErrorConvert, err.Error())
+		return apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
 	}
 
-	filter := bson.M{"_id": oid}
+	filter := versionFilter(notDeleted(bson.M{"_id": oid}), ifMatch)
+	update := bson.M{
+		"$set": bson.M{"deleted_at": deletedAt},
+		"$inc": bson.M{"version": 1},
+	}
 
-	result, err := userRepository.collection.DeleteOne(ctx, filter)
+	result, err := userRepository.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		err = errors.Wrap(err, "failed to delete user")
 		return apperror.NewAppError(apperror.ErrorDeleteOne, err.Error())
 	}
 
-	if result.DeletedCount == 0 {
-		err = errors.New("can not be deleted: failed to get user in database for delete")
-		return apperror.NewAppError(apperror.ErrorDeleteOne, err.Error())
+	if result.MatchedCount == 0 {
+		return userRepository.unmatchedUserError(ctx, oid, ifMatch)
 	}
 
 	return nil
 }
+
+func (userRepository *UserRepository) RestoreUser(ctx context.Context, id string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.RestoreUser")
+	defer span.End()
+
+	var repositoryUser *User
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = errors.Wrap(err, "failed to convert user id to oid")
+		return nil, apperror.NewAppError(apperror.ErrorInvalidID, err.Error())
+	}
+
+	filter := bson.M{"_id": oid, "deleted_at": bson.M{"$type": "date"}}
+	update := bson.M{
+		"$set": bson.M{"deleted_at": nil},
+		"$inc": bson.M{"version": 1},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	// the email of a deleted account is free to register again; if it has
+	// been taken since, the account stays deleted
+	result := userRepository.collection.FindOneAndUpdate(ctx, filter, update, opts)
+	if mongo.IsDuplicateKeyError(result.Err()) {
+		return nil, apperror.NewAppError(apperror.ErrorUserEmailTaken, "another user has registered with the email of this user")
+	}
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, apperror.NewAppError(apperror.ErrorUserNotFound, "deleted user with this id does not exist")
+	}
+	if result.Err() != nil {
+		err = errors.Wrap(result.Err(), "failed to restore user")
+		return nil, apperror.NewAppError(apperror.ErrorUpdateOne, err.Error())
+	}
+
+	if err = result.Decode(&repositoryUser); err != nil {
+		err = errors.Wrap(err, "failed to decode user mongo object to struct")
+		return nil, apperror.NewAppError(apperror.ErrorDecode, err.Error())
+	}
+
+	domainUser := userToDomain(repositoryUser)
+
+	return &domainUser, nil
+}
+
+// PurgeDeletedUsers permanently removes the accounts soft deleted before the
+// given time and returns how many were removed.
+func (userRepository *UserRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.PurgeDeletedUsers")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+
+	defer cancel()
+
+	filter := bson.M{"deleted_at": bson.M{"$lte": deletedBefore}}
+
+	result, err := userRepository.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		err = errors.Wrap(err, "failed to purge deleted users")
+		return 0, apperror.NewAppError(apperror.ErrorDeleteOne, err.Error())
+	}
+
+	return result.DeletedCount, nil
+}
+
+// findOneAndUpdate applies a versioned write to the user and returns the
+// updated document. With ifMatch set the write only succeeds when the stored
+// version is one of the given versions.
+func (userRepository *UserRepository) findOneAndUpdate(ctx context.Context, oid primitive.ObjectID, ifMatch []int64, update interface{}) (*domain.User, error) {
+	var repositoryUser *User
+
+	filter := versionFilter(notDeleted(bson.M{"_id": oid}), ifMatch)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	result := userRepository.collection.FindOneAndUpdate(ctx, filter, update, opts)
+	if mongo.IsDuplicateKeyError(result.Err()) {
+		return nil, apperror.NewAppError(apperror.ErrorUserEmailTaken, "user with this email already exists")
+	}
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, userRepository.unmatchedUserError(ctx, oid, ifMatch)
+	}
+	if result.Err() != nil {
+		err := errors.Wrap(result.Err(), "failed to update user")
+		return nil, apperror.NewAppError(apperror.ErrorUpdateOne, err.Error())
+	}
+
+	if err := result.Decode(&repositoryUser); err != nil {
+		err = errors.Wrap(err, "failed to decode user mongo object to struct")
+		return nil, apperror.NewAppError(apperror.ErrorDecode, err.Error())
+	}
+
+	domainUser := userToDomain(repositoryUser)
+
+	return &domainUser, nil
+}
+
+// unmatchedUserError tells a missing user apart from a conditional write that
+// lost against a concurrent one.
+func (userRepository *UserRepository) unmatchedUserError(ctx context.Context, oid primitive.ObjectID, ifMatch []int64) error {
+	if len(ifMatch) > 0 {
+		count, err := userRepository.collection.CountDocuments(ctx, notDeleted(bson.M{"_id": oid}))
+		if err != nil {
+			err = errors.Wrap(err, "failed to check user existence")
+			return apperror.NewAppError(apperror.ErrorGetOne, err.Error())
+		}
+
+		if count > 0 {
+			return apperror.NewAppError(apperror.ErrorUserVersionMismatch, "user has been modified since it was read")
+		}
+	}
+
+	return apperror.NewAppError(apperror.ErrorUserNotFound, "user with this id does not exist")
+}
@@ -0,0 +1,167 @@
+package usecase_auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/pkg/token"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const refreshTokenSize = 32
+
+const TokenTypeBearer = "Bearer"
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *domain.Session) (string, error)
+	GetSessionByTokenHash(ctx context.Context, tokenHash string) (*domain.Session, error)
+	UseSession(ctx context.Context, id string) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+}
+
+type UserProvider interface {
+	VerifyCredentials(ctx context.Context, email, password string) (*domain.User, error)
+	GetUserInfo(ctx context.Context, id string) (*domain.User, error)
+}
+
+type AccessTokenIssuer interface {
+	Generate(subject, role string) (string, error)
+	TTL() time.Duration
+}
+
+type AuthUsecaseDeps struct {
+	SessionRepository SessionRepository
+	UserProvider      UserProvider
+	AccessTokenIssuer AccessTokenIssuer
+	RefreshTTL        time.Duration
+}
+
+type AuthUsecase struct {
+	sessionRepository SessionRepository
+	userProvider      UserProvider
+	accessTokenIssuer AccessTokenIssuer
+	refreshTTL        time.Duration
+}
+
+func NewAuthUsecase(deps *AuthUsecaseDeps) *AuthUsecase {
+	return &AuthUsecase{
+		sessionRepository: deps.SessionRepository,
+		userProvider:      deps.UserProvider,
+		accessTokenIssuer: deps.AccessTokenIssuer,
+		refreshTTL:        deps.RefreshTTL,
+	}
+}
+
+func (authUsecase *AuthUsecase) Login(ctx context.Context, email, password string) (*domain.TokenPair, error) {
+	user, err := authUsecase.userProvider.VerifyCredentials(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsVerified() {
+		return nil, apperror.NewAppError(apperror.ErrorUnverified, "confirm your email address before logging in")
+	}
+
+	return authUsecase.issueTokens(ctx, user, uuid.NewString())
+}
+
+// Refresh rotates the refresh token: the presented token is consumed and a new
+// pair is issued within the same family. Presenting an already consumed token
+// means it leaked, so the whole family is revoked.
+func (authUsecase *AuthUsecase) Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, error) {
+	logger := zerolog.Ctx(ctx)
+
+	session, err := authUsecase.sessionRepository.GetSessionByTokenHash(ctx, token.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, apperror.ErrorNotFound) {
+			return nil, apperror.NewAppError(apperror.ErrorToken, "refresh token is not valid")
+		}
+		return nil, err
+	}
+
+	if session.RevokedAt != nil {
+		return nil, apperror.NewAppError(apperror.ErrorToken, "refresh token is revoked")
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		return nil, apperror.NewAppError(apperror.ErrorToken, "refresh token is expired")
+	}
+
+	used, err := authUsecase.sessionRepository.UseSession(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !used {
+		logger.Warn().
+			Str("user_id", session.UserID).
+			Str("family_id", session.FamilyID).
+			Msg("refresh token reuse detected, revoking session family")
+
+		if err = authUsecase.sessionRepository.RevokeFamily(ctx, session.FamilyID); err != nil {
+			return nil, err
+		}
+
+		return nil, apperror.NewAppError(apperror.ErrorToken, "refresh token is already used")
+	}
+
+	user, err := authUsecase.userProvider.GetUserInfo(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrorNotFound) {
+			return nil, apperror.NewAppError(apperror.ErrorToken, "refresh token owner does not exist")
+		}
+		return nil, err
+	}
+
+	return authUsecase.issueTokens(ctx, user, session.FamilyID)
+}
+
+func (authUsecase *AuthUsecase) Logout(ctx context.Context, refreshToken string) error {
+	session, err := authUsecase.sessionRepository.GetSessionByTokenHash(ctx, token.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, apperror.ErrorNotFound) {
+			return apperror.NewAppError(apperror.ErrorToken, "refresh token is not valid")
+		}
+		return err
+	}
+
+	return authUsecase.sessionRepository.RevokeFamily(ctx, session.FamilyID)
+}
+
+func (authUsecase *AuthUsecase) issueTokens(ctx context.Context, user *domain.User, familyID string) (*domain.TokenPair, error) {
+	accessToken, err := authUsecase.accessTokenIssuer.Generate(user.ID, user.Role)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.ErrorGenerateToken, err.Error())
+	}
+
+	refreshToken, err := token.NewRandomToken(refreshTokenSize)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.ErrorGenerateToken, err.Error())
+	}
+
+	now := time.Now()
+
+	session := &domain.Session{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: token.HashToken(refreshToken),
+		CreatedAt: now,
+		ExpiresAt: now.Add(authUsecase.refreshTTL),
+	}
+
+	if _, err = authUsecase.sessionRepository.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return &domain.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    TokenTypeBearer,
+		ExpiresIn:    int64(authUsecase.accessTokenIssuer.TTL().Seconds()),
+	}, nil
+}
@@ -0,0 +1,221 @@
+package usecase_auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/pkg/token"
+)
+
+// sessionStore keeps sessions in memory and consumes them the way the mongo
+// repository does: only the first use of a session succeeds.
+type sessionStore struct {
+	sessions map[string]*domain.Session
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{sessions: make(map[string]*domain.Session)}
+}
+
+func (s *sessionStore) CreateSession(_ context.Context, session *domain.Session) (string, error) {
+	session.ID = strconv.Itoa(len(s.sessions) + 1)
+	s.sessions[session.ID] = session
+
+	return session.ID, nil
+}
+
+func (s *sessionStore) GetSessionByTokenHash(_ context.Context, tokenHash string) (*domain.Session, error) {
+	for _, session := range s.sessions {
+		if session.TokenHash == tokenHash {
+			found := *session
+			return &found, nil
+		}
+	}
+
+	return nil, apperror.NewAppError(apperror.ErrorNotFound, "session does not exist")
+}
+
+func (s *sessionStore) UseSession(_ context.Context, id string) (bool, error) {
+	session := s.sessions[id]
+	if session.UsedAt != nil || session.RevokedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	session.UsedAt = &now
+
+	return true, nil
+}
+
+func (s *sessionStore) RevokeFamily(_ context.Context, familyID string) error {
+	now := time.Now()
+
+	for _, session := range s.sessions {
+		if session.FamilyID == familyID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+
+	return nil
+}
+
+type userProvider struct {
+	user *domain.User
+}
+
+func (p *userProvider) VerifyCredentials(context.Context, string, string) (*domain.User, error) {
+	return p.user, nil
+}
+
+func (p *userProvider) GetUserInfo(context.Context, string) (*domain.User, error) {
+	return p.user, nil
+}
+
+type tokenIssuer struct{}
+
+func (tokenIssuer) Generate(subject, _ string) (string, error) {
+	return "access-" + subject, nil
+}
+
+func (tokenIssuer) TTL() time.Duration {
+	return 15 * time.Minute
+}
+
+func newTestUsecase(sessions *sessionStore) *AuthUsecase {
+	verifiedAt := time.Now()
+
+	return NewAuthUsecase(&AuthUsecaseDeps{
+		SessionRepository: sessions,
+		UserProvider:      &userProvider{user: &domain.User{ID: "user-1", Role: domain.RoleUser, VerifiedAt: &verifiedAt}},
+		AccessTokenIssuer: tokenIssuer{},
+		RefreshTTL:        time.Hour,
+	})
+}
+
+func TestRefreshRotatesWithinFamily(t *testing.T) {
+	sessions := newSessionStore()
+	usecase := newTestUsecase(sessions)
+	ctx := context.Background()
+
+	first, err := usecase.Login(ctx, "user@example.com", "secret")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	second, err := usecase.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh returned the presented refresh token")
+	}
+
+	if _, err = usecase.Refresh(ctx, second.RefreshToken); err != nil {
+		t.Fatalf("refresh with the rotated token: %v", err)
+	}
+
+	if len(sessions.sessions) != 3 {
+		t.Fatalf("got %d sessions, want 3", len(sessions.sessions))
+	}
+
+	familyID := sessions.sessions["1"].FamilyID
+	for _, session := range sessions.sessions {
+		if session.FamilyID != familyID {
+			t.Fatalf("session %s is in family %s, want %s", session.ID, session.FamilyID, familyID)
+		}
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	sessions := newSessionStore()
+	usecase := newTestUsecase(sessions)
+	ctx := context.Background()
+
+	first, err := usecase.Login(ctx, "user@example.com", "secret")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	second, err := usecase.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	if _, err = usecase.Refresh(ctx, first.RefreshToken); !errors.Is(err, apperror.ErrorToken) {
+		t.Fatalf("reusing a consumed token: got %v, want %v", err, apperror.ErrorToken)
+	}
+
+	for _, session := range sessions.sessions {
+		if session.RevokedAt == nil {
+			t.Fatalf("session %s of the reused family is not revoked", session.ID)
+		}
+	}
+
+	if _, err = usecase.Refresh(ctx, second.RefreshToken); !errors.Is(err, apperror.ErrorToken) {
+		t.Fatalf("refresh after reuse: got %v, want %v", err, apperror.ErrorToken)
+	}
+}
+
+func TestRefreshRejectsInvalidTokens(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		session *domain.Session
+		token   string
+	}{
+		{
+			name:  "unknown token",
+			token: "unknown",
+		},
+		{
+			name:    "expired token",
+			session: &domain.Session{ExpiresAt: past},
+			token:   "expired",
+		},
+		{
+			name:    "revoked token",
+			session: &domain.Session{ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &past},
+			token:   "revoked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := newSessionStore()
+			usecase := newTestUsecase(sessions)
+
+			if tt.session != nil {
+				tt.session.FamilyID = "family-1"
+				tt.session.TokenHash = token.HashToken(tt.token)
+
+				if _, err := sessions.CreateSession(context.Background(), tt.session); err != nil {
+					t.Fatalf("create session: %v", err)
+				}
+			}
+
+			if _, err := usecase.Refresh(context.Background(), tt.token); !errors.Is(err, apperror.ErrorToken) {
+				t.Fatalf("got %v, want %v", err, apperror.ErrorToken)
+			}
+
+			if tt.session != nil && sessions.sessions[tt.session.ID].UsedAt != nil {
+				t.Fatal("rejected token was consumed")
+			}
+		})
+	}
+}
+
+func TestLoginRequiresVerifiedEmail(t *testing.T) {
+	usecase := newTestUsecase(newSessionStore())
+	usecase.userProvider = &userProvider{user: &domain.User{ID: "user-1", Role: domain.RoleUser}}
+
+	if _, err := usecase.Login(context.Background(), "user@example.com", "secret"); !errors.Is(err, apperror.ErrorUnverified) {
+		t.Fatalf("got %v, want %v", err, apperror.ErrorUnverified)
+	}
+}
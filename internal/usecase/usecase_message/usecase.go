@@ -0,0 +1,214 @@
+package usecase_message
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/utils"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 100
+	MaxBodyLength    = 4000
+)
+
+type MessageRepository interface {
+	CreateMessage(ctx context.Context, message *domain.Message) (string, error)
+	GetMessage(ctx context.Context, id string) (*domain.Message, error)
+	GetMessages(ctx context.Context, cursor *domain.MessageCursor) (*domain.MessagePage, error)
+	EditMessage(ctx context.Context, id, body string, editedAt time.Time) error
+	DeleteMessage(ctx context.Context, id string, deletedAt time.Time) error
+}
+
+type RoomProvider interface {
+	GetRoom(ctx context.Context, principal *domain.Principal, id string) (*domain.Room, error)
+}
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event *domain.Event) error
+}
+
+type MetricsRecorder interface {
+	RecordMessageSent()
+}
+
+type MessageUsecaseDeps struct {
+	MessageRepository MessageRepository
+	RoomProvider      RoomProvider
+	EventPublisher    EventPublisher
+	MetricsRecorder   MetricsRecorder
+}
+
+type MessageUsecase struct {
+	messageRepository MessageRepository
+	roomProvider      RoomProvider
+	eventPublisher    EventPublisher
+	metricsRecorder   MetricsRecorder
+}
+
+func NewMessageUsecase(deps *MessageUsecaseDeps) *MessageUsecase {
+	return &MessageUsecase{
+		messageRepository: deps.MessageRepository,
+		roomProvider:      deps.RoomProvider,
+		eventPublisher:    deps.EventPublisher,
+		metricsRecorder:   deps.MetricsRecorder,
+	}
+}
+
+func (messageUsecase *MessageUsecase) SendMessage(ctx context.Context, principal *domain.Principal, roomID, body string) (*domain.Message, error) {
+	if err := validateBody(body); err != nil {
+		return nil, err
+	}
+
+	room, err := messageUsecase.roomProvider.GetRoom(ctx, principal, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if room.IsArchived() {
+		return nil, apperror.NewAppError(apperror.ErrorRoomArchived, "messages can not be sent to an archived room")
+	}
+
+	if room.Type != domain.RoomTypePublic && !room.HasMember(principal.UserID) {
+		return nil, apperror.NewAppError(apperror.ErrorForbidden, "caller is not a member of this room")
+	}
+
+	message := &domain.Message{
+		RoomID:    roomID,
+		AuthorID:  principal.UserID,
+		Body:      body,
+		CreatedAt: time.Now(),
+	}
+
+	id, err := messageUsecase.messageRepository.CreateMessage(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+
+	message.ID = id
+
+	messageUsecase.metricsRecorder.RecordMessageSent()
+	messageUsecase.publish(ctx, domain.NewMessageEvent(domain.EventMessageCreated, message))
+
+	return message, nil
+}
+
+func (messageUsecase *MessageUsecase) GetHistory(ctx context.Context, principal *domain.Principal, cursor *domain.MessageCursor) (*domain.MessagePage, error) {
+	if cursor.Before != "" && cursor.After != "" {
+		return nil, apperror.NewAppError(apperror.ErrorGetUrlParams, "before and after can not be used together")
+	}
+
+	switch {
+	case cursor.Limit <= 0:
+		cursor.Limit = DefaultPageLimit
+	case cursor.Limit > MaxPageLimit:
+		cursor.Limit = MaxPageLimit
+	}
+
+	if _, err := messageUsecase.roomProvider.GetRoom(ctx, principal, cursor.RoomID); err != nil {
+		return nil, err
+	}
+
+	page, err := messageUsecase.messageRepository.GetMessages(ctx, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (messageUsecase *MessageUsecase) EditMessage(ctx context.Context, principal *domain.Principal, roomID, id, body string) error {
+	if err := validateBody(body); err != nil {
+		return err
+	}
+
+	room, message, err := messageUsecase.getRoomMessage(ctx, principal, roomID, id)
+	if err != nil {
+		return err
+	}
+
+	if room.IsArchived() {
+		return apperror.NewAppError(apperror.ErrorRoomArchived, "messages can not be edited in an archived room")
+	}
+
+	if message.AuthorID != principal.UserID {
+		return apperror.NewAppError(apperror.ErrorForbidden, "only the author can edit this message")
+	}
+
+	editedAt := time.Now()
+
+	if err = messageUsecase.messageRepository.EditMessage(ctx, id, body, editedAt); err != nil {
+		return err
+	}
+
+	message.Body = body
+	message.EditedAt = &editedAt
+
+	messageUsecase.publish(ctx, domain.NewMessageEvent(domain.EventMessageEdited, message))
+
+	return nil
+}
+
+func (messageUsecase *MessageUsecase) DeleteMessage(ctx context.Context, principal *domain.Principal, roomID, id string) error {
+	room, message, err := messageUsecase.getRoomMessage(ctx, principal, roomID, id)
+	if err != nil {
+		return err
+	}
+
+	if message.AuthorID != principal.UserID && room.OwnerID != principal.UserID && !principal.IsAdmin() {
+		return apperror.NewAppError(apperror.ErrorForbidden, "only the author or the room owner can delete this message")
+	}
+
+	deletedAt := time.Now()
+
+	if err = messageUsecase.messageRepository.DeleteMessage(ctx, id, deletedAt); err != nil {
+		return err
+	}
+
+	message.Body = utils.EmptyString
+	message.DeletedAt = &deletedAt
+
+	messageUsecase.publish(ctx, domain.NewMessageEvent(domain.EventMessageDeleted, message))
+
+	return nil
+}
+
+func (messageUsecase *MessageUsecase) getRoomMessage(ctx context.Context, principal *domain.Principal, roomID, id string) (*domain.Room, *domain.Message, error) {
+	room, err := messageUsecase.roomProvider.GetRoom(ctx, principal, roomID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	message, err := messageUsecase.messageRepository.GetMessage(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if message.RoomID != roomID || message.IsDeleted() {
+		return nil, nil, apperror.NewAppError(apperror.ErrorMessageNotFound, "message with this id does not exist")
+	}
+
+	return room, message, nil
+}
+
+func validateBody(body string) error {
+	if body == utils.EmptyString || utf8.RuneCountInString(body) > MaxBodyLength {
+		return apperror.NewAppError(apperror.ErrorValidatePayload, "message body must be between 1 and 4000 characters")
+	}
+
+	return nil
+}
+
+// publish notifies real-time subscribers. The message is already persisted at
+// this point, so a delivery failure is logged instead of failing the request.
+func (messageUsecase *MessageUsecase) publish(ctx context.Context, event *domain.Event) {
+	if err := messageUsecase.eventPublisher.Publish(ctx, event); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("room_id", event.RoomID).Str("type", event.Type).Msg("publish room event")
+	}
+}
@@ -0,0 +1,322 @@
+package usecase_message
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+)
+
+type messageStore struct {
+	messages map[string]*domain.Message
+	cursor   *domain.MessageCursor
+	writes   int
+}
+
+func (s *messageStore) CreateMessage(_ context.Context, message *domain.Message) (string, error) {
+	s.writes++
+	return "message-created", nil
+}
+
+func (s *messageStore) GetMessage(_ context.Context, id string) (*domain.Message, error) {
+	message, ok := s.messages[id]
+	if !ok {
+		return nil, apperror.NewAppError(apperror.ErrorMessageNotFound, "message with this id does not exist")
+	}
+
+	found := *message
+	return &found, nil
+}
+
+func (s *messageStore) GetMessages(_ context.Context, cursor *domain.MessageCursor) (*domain.MessagePage, error) {
+	s.cursor = cursor
+	return &domain.MessagePage{}, nil
+}
+
+func (s *messageStore) EditMessage(context.Context, string, string, time.Time) error {
+	s.writes++
+	return nil
+}
+
+func (s *messageStore) DeleteMessage(context.Context, string, time.Time) error {
+	s.writes++
+	return nil
+}
+
+type roomProvider struct {
+	room *domain.Room
+}
+
+func (p *roomProvider) GetRoom(_ context.Context, principal *domain.Principal, _ string) (*domain.Room, error) {
+	if p.room.Type != domain.RoomTypePublic && !p.room.HasMember(principal.UserID) && !principal.IsAdmin() {
+		return nil, apperror.NewAppError(apperror.ErrorForbidden, "caller is not a member of this room")
+	}
+
+	return p.room, nil
+}
+
+type eventRecorder struct {
+	events []*domain.Event
+}
+
+func (r *eventRecorder) Publish(_ context.Context, event *domain.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+type sentCounter struct {
+	sent int
+}
+
+func (c *sentCounter) RecordMessageSent() {
+	c.sent++
+}
+
+type testUsecase struct {
+	*MessageUsecase
+	messages *messageStore
+	events   *eventRecorder
+}
+
+func newTestUsecase(room *domain.Room, messages ...*domain.Message) *testUsecase {
+	store := &messageStore{messages: make(map[string]*domain.Message)}
+	for _, message := range messages {
+		store.messages[message.ID] = message
+	}
+
+	events := &eventRecorder{}
+
+	return &testUsecase{
+		MessageUsecase: NewMessageUsecase(&MessageUsecaseDeps{
+			MessageRepository: store,
+			RoomProvider:      &roomProvider{room: room},
+			EventPublisher:    events,
+			MetricsRecorder:   &sentCounter{},
+		}),
+		messages: store,
+		events:   events,
+	}
+}
+
+var (
+	author    = &domain.Principal{UserID: "author", Role: domain.RoleUser}
+	roomOwner = &domain.Principal{UserID: "owner", Role: domain.RoleUser}
+	outsider  = &domain.Principal{UserID: "outsider", Role: domain.RoleUser}
+)
+
+func privateRoom() *domain.Room {
+	return &domain.Room{ID: "room", Type: domain.RoomTypePrivate, OwnerID: "owner", Members: []string{"owner", "author"}}
+}
+
+func archivedRoom() *domain.Room {
+	room := privateRoom()
+	archivedAt := time.Now()
+	room.ArchivedAt = &archivedAt
+
+	return room
+}
+
+func TestSendMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		room      *domain.Room
+		principal *domain.Principal
+		body      string
+		wantErr   error
+	}{
+		{name: "member sends", room: privateRoom(), principal: author, body: "hello"},
+		{name: "body of the maximum length", room: privateRoom(), principal: author, body: strings.Repeat("ж", MaxBodyLength)},
+		{name: "empty body", room: privateRoom(), principal: author, wantErr: apperror.ErrorValidatePayload},
+		{name: "body too long", room: privateRoom(), principal: author, body: strings.Repeat("a", MaxBodyLength+1), wantErr: apperror.ErrorValidatePayload},
+		{name: "archived room", room: archivedRoom(), principal: author, body: "hello", wantErr: apperror.ErrorRoomArchived},
+		{name: "non member", room: privateRoom(), principal: outsider, body: "hello", wantErr: apperror.ErrorForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := newTestUsecase(tt.room)
+
+			message, err := usecase.SendMessage(context.Background(), tt.principal, "room", tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				if usecase.messages.writes != 0 || len(usecase.events.events) != 0 {
+					t.Fatal("rejected message was stored or published")
+				}
+				return
+			}
+
+			if message.ID != "message-created" || message.AuthorID != tt.principal.UserID {
+				t.Fatalf("got message %+v", message)
+			}
+
+			if len(usecase.events.events) != 1 || usecase.events.events[0].Type != domain.EventMessageCreated {
+				t.Fatalf("got events %+v, want one %s", usecase.events.events, domain.EventMessageCreated)
+			}
+		})
+	}
+}
+
+func TestEditMessage(t *testing.T) {
+	deletedAt := time.Now()
+
+	tests := []struct {
+		name      string
+		room      *domain.Room
+		message   *domain.Message
+		principal *domain.Principal
+		body      string
+		wantErr   error
+	}{
+		{
+			name:      "author edits",
+			room:      privateRoom(),
+			message:   &domain.Message{ID: "message", RoomID: "room", AuthorID: "author"},
+			principal: author,
+			body:      "edited",
+		},
+		{
+			name:      "other member can not edit",
+			room:      privateRoom(),
+			message:   &domain.Message{ID: "message", RoomID: "room", AuthorID: "author"},
+			principal: roomOwner,
+			body:      "edited",
+			wantErr:   apperror.ErrorForbidden,
+		},
+		{
+			name:      "empty body",
+			room:      privateRoom(),
+			message:   &domain.Message{ID: "message", RoomID: "room", AuthorID: "author"},
+			principal: author,
+			wantErr:   apperror.ErrorValidatePayload,
+		},
+		{
+			name:      "body too long",
+			room:      privateRoom(),
+			message:   &domain.Message{ID: "message", RoomID: "room", AuthorID: "author"},
+			principal: author,
+			body:      strings.Repeat("a", MaxBodyLength+1),
+			wantErr:   apperror.ErrorValidatePayload,
+		},
+		{
+			name:      "archived room",
+			room:      archivedRoom(),
+			message:   &domain.Message{ID: "message", RoomID: "room", AuthorID: "author"},
+			principal: author,
+			body:      "edited",
+			wantErr:   apperror.ErrorRoomArchived,
+		},
+		{
+			name:      "message of another room",
+			room:      privateRoom(),
+			message:   &domain.Message{ID: "message", RoomID: "other-room", AuthorID: "author"},
+			principal: author,
+			body:      "edited",
+			wantErr:   apperror.ErrorMessageNotFound,
+		},
+		{
+			name:      "deleted message",
+			room:      privateRoom(),
+			message:   &domain.Message{ID: "message", RoomID: "room", AuthorID: "author", DeletedAt: &deletedAt},
+			principal: author,
+			body:      "edited",
+			wantErr:   apperror.ErrorMessageNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := newTestUsecase(tt.room, tt.message)
+
+			err := usecase.EditMessage(context.Background(), tt.principal, "room", "message", tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+
+			wantWrites := 1
+			if tt.wantErr != nil {
+				wantWrites = 0
+			}
+
+			if usecase.messages.writes != wantWrites || len(usecase.events.events) != wantWrites {
+				t.Fatalf("got %d writes and %d events, want %d", usecase.messages.writes, len(usecase.events.events), wantWrites)
+			}
+		})
+	}
+}
+
+func TestDeleteMessagePermissions(t *testing.T) {
+	admin := &domain.Principal{UserID: "admin", Role: domain.RoleAdmin}
+	member := &domain.Principal{UserID: "member", Role: domain.RoleUser}
+
+	tests := []struct {
+		name      string
+		principal *domain.Principal
+		wantErr   error
+	}{
+		{name: "author", principal: author},
+		{name: "room owner", principal: roomOwner},
+		{name: "admin", principal: admin},
+		{name: "other member", principal: member, wantErr: apperror.ErrorForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := privateRoom()
+			room.Members = append(room.Members, "member")
+
+			usecase := newTestUsecase(room, &domain.Message{ID: "message", RoomID: "room", AuthorID: "author", Body: "hello"})
+
+			err := usecase.DeleteMessage(context.Background(), tt.principal, "room", "message")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil {
+				event := usecase.events.events[0]
+				if event.Type != domain.EventMessageDeleted {
+					t.Fatalf("got event %s, want %s", event.Type, domain.EventMessageDeleted)
+				}
+			}
+		})
+	}
+}
+
+func TestGetHistoryLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		cursor    *domain.MessageCursor
+		wantLimit int
+		wantErr   error
+	}{
+		{name: "default limit", cursor: &domain.MessageCursor{RoomID: "room"}, wantLimit: DefaultPageLimit},
+		{name: "limit is capped", cursor: &domain.MessageCursor{RoomID: "room", Limit: MaxPageLimit + 1}, wantLimit: MaxPageLimit},
+		{name: "limit is kept", cursor: &domain.MessageCursor{RoomID: "room", Limit: 10}, wantLimit: 10},
+		{
+			name:    "before and after together",
+			cursor:  &domain.MessageCursor{RoomID: "room", Before: "a", After: "b"},
+			wantErr: apperror.ErrorGetUrlParams,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := newTestUsecase(privateRoom())
+
+			_, err := usecase.GetHistory(context.Background(), author, tt.cursor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && usecase.messages.cursor.Limit != tt.wantLimit {
+				t.Fatalf("got limit %d, want %d", usecase.messages.cursor.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
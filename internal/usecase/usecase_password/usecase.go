@@ -0,0 +1,196 @@
+package usecase_password
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/pkg/mailer"
+	"github.com/Meystergod/gochat/pkg/token"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const resetTokenSize = 32
+
+const resetTokenParam = "token"
+
+// sendTimeout bounds looking up the user and mailing the reset link, which
+// happen after the request has been answered.
+const sendTimeout = 30 * time.Second
+
+type UserTokenRepository interface {
+	CreateUserToken(ctx context.Context, token *domain.UserToken) (string, error)
+	UseUserToken(ctx context.Context, purpose, tokenHash string, usedAt time.Time) (*domain.UserToken, error)
+	DeleteUserTokens(ctx context.Context, userID, purpose string) error
+}
+
+type UserRepository interface {
+	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	UpdateUserPassword(ctx context.Context, id string, passwordHash string) error
+}
+
+type SessionRevoker interface {
+	RevokeUserSessions(ctx context.Context, userID string) error
+}
+
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message *mailer.Message) error
+}
+
+type PasswordUsecaseDeps struct {
+	UserTokenRepository UserTokenRepository
+	UserRepository      UserRepository
+	SessionRevoker      SessionRevoker
+	PasswordHasher      PasswordHasher
+	Mailer              Mailer
+	TokenTTL            time.Duration
+	ResetURL            string
+}
+
+type PasswordUsecase struct {
+	userTokenRepository UserTokenRepository
+	userRepository      UserRepository
+	sessionRevoker      SessionRevoker
+	passwordHasher      PasswordHasher
+	mailer              Mailer
+	tokenTTL            time.Duration
+	resetURL            string
+}
+
+func NewPasswordUsecase(deps *PasswordUsecaseDeps) *PasswordUsecase {
+	return &PasswordUsecase{
+		userTokenRepository: deps.UserTokenRepository,
+		userRepository:      deps.UserRepository,
+		sessionRevoker:      deps.SessionRevoker,
+		passwordHasher:      deps.PasswordHasher,
+		mailer:              deps.Mailer,
+		tokenTTL:            deps.TokenTTL,
+		resetURL:            deps.ResetURL,
+	}
+}
+
+// ForgotPassword mails a password reset link to the account with the given
+// email. The lookup and the mail happen in the background, detached from the
+// request, so that the endpoint answers known and unknown emails alike and in
+// the same time; failures are only logged.
+func (passwordUsecase *PasswordUsecase) ForgotPassword(ctx context.Context, email string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sendTimeout)
+		defer cancel()
+
+		passwordUsecase.forgotPassword(ctx, email)
+	}()
+}
+
+// ResetPassword redeems a reset token, stores the new password and revokes
+// every session of the user, so that whoever held the old password is logged
+// out everywhere.
+func (passwordUsecase *PasswordUsecase) ResetPassword(ctx context.Context, rawToken, password string) error {
+	logger := zerolog.Ctx(ctx)
+
+	userToken, err := passwordUsecase.userTokenRepository.UseUserToken(
+		ctx, domain.TokenPurposePasswordReset, token.HashToken(rawToken), time.Now(),
+	)
+	if err != nil {
+		if errors.Is(err, apperror.ErrorNotFound) {
+			return apperror.NewAppError(apperror.ErrorToken, "password reset token is not valid")
+		}
+		return err
+	}
+
+	passwordHash, err := passwordUsecase.passwordHasher.Hash(password)
+	if err != nil {
+		return apperror.NewAppError(apperror.ErrorHashPassword, err.Error())
+	}
+
+	if err = passwordUsecase.userRepository.UpdateUserPassword(ctx, userToken.UserID, passwordHash); err != nil {
+		return err
+	}
+
+	if err = passwordUsecase.sessionRevoker.RevokeUserSessions(ctx, userToken.UserID); err != nil {
+		return err
+	}
+
+	err = passwordUsecase.userTokenRepository.DeleteUserTokens(ctx, userToken.UserID, domain.TokenPurposePasswordReset)
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", userToken.UserID).Msg("delete remaining password reset tokens")
+	}
+
+	logger.Info().Str("user_id", userToken.UserID).Msg("reset user password")
+
+	return nil
+}
+
+func (passwordUsecase *PasswordUsecase) forgotPassword(ctx context.Context, email string) {
+	logger := zerolog.Ctx(ctx)
+
+	user, err := passwordUsecase.userRepository.GetUserByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, apperror.ErrorNotFound) {
+			logger.Error().Err(err).Msg("look up user for password reset")
+		}
+		return
+	}
+
+	if err = passwordUsecase.sendResetToken(ctx, user); err != nil {
+		logger.Error().Err(err).Str("user_id", user.ID).Msg("send password reset email")
+	}
+}
+
+func (passwordUsecase *PasswordUsecase) sendResetToken(ctx context.Context, user *domain.User) error {
+	rawToken, err := token.NewRandomToken(resetTokenSize)
+	if err != nil {
+		return apperror.NewAppError(apperror.ErrorGenerateToken, err.Error())
+	}
+
+	err = passwordUsecase.userTokenRepository.DeleteUserTokens(ctx, user.ID, domain.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	_, err = passwordUsecase.userTokenRepository.CreateUserToken(ctx, &domain.UserToken{
+		UserID:    user.ID,
+		Purpose:   domain.TokenPurposePasswordReset,
+		TokenHash: token.HashToken(rawToken),
+		CreatedAt: now,
+		ExpiresAt: now.Add(passwordUsecase.tokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(passwordUsecase.resetURL)
+	if err != nil {
+		return errors.Wrap(err, "parsing password reset url")
+	}
+
+	query := link.Query()
+	query.Set(resetTokenParam, rawToken)
+	link.RawQuery = query.Encode()
+
+	message := &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\r\n\r\nsomeone asked to reset the password of your account. If it was you, open the link below:\r\n\r\n%s\r\n\r\nThe link expires in %s. If you did not ask for it, ignore this email.\r\n",
+			user.Name, link.String(), passwordUsecase.tokenTTL,
+		),
+	}
+
+	if err = passwordUsecase.mailer.Send(ctx, message); err != nil {
+		return errors.Wrap(err, "sending password reset email")
+	}
+
+	return nil
+}
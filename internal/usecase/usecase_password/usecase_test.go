@@ -0,0 +1,193 @@
+package usecase_password
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/pkg/mailer"
+)
+
+// resetTokens keeps user tokens in memory. Like the mongo repository it lets
+// a token be used once and only before it expires.
+type resetTokens struct {
+	tokens []*domain.UserToken
+}
+
+func (r *resetTokens) CreateUserToken(_ context.Context, userToken *domain.UserToken) (string, error) {
+	r.tokens = append(r.tokens, userToken)
+	return "token", nil
+}
+
+func (r *resetTokens) UseUserToken(_ context.Context, purpose, tokenHash string, usedAt time.Time) (*domain.UserToken, error) {
+	for _, userToken := range r.tokens {
+		if userToken.Purpose == purpose && userToken.TokenHash == tokenHash && userToken.UsedAt == nil && userToken.ExpiresAt.After(usedAt) {
+			userToken.UsedAt = &usedAt
+			return userToken, nil
+		}
+	}
+
+	return nil, apperror.NewAppError(apperror.ErrorNotFound, "user token does not exist, expired or was already used")
+}
+
+func (r *resetTokens) DeleteUserTokens(_ context.Context, userID, purpose string) error {
+	var kept []*domain.UserToken
+	for _, userToken := range r.tokens {
+		if userToken.UserID != userID || userToken.Purpose != purpose {
+			kept = append(kept, userToken)
+		}
+	}
+
+	r.tokens = kept
+
+	return nil
+}
+
+// account is the single user of a test, together with what happened to it.
+type account struct {
+	user            *domain.User
+	revokedSessions int
+	mails           []*mailer.Message
+}
+
+func (a *account) GetUserByEmail(_ context.Context, email string) (*domain.User, error) {
+	if email != a.user.Email {
+		return nil, apperror.NewAppError(apperror.ErrorUserNotFound, "user with this email does not exist")
+	}
+
+	return a.user, nil
+}
+
+func (a *account) UpdateUserPassword(_ context.Context, id string, passwordHash string) error {
+	a.user.Password = passwordHash
+	return nil
+}
+
+func (a *account) RevokeUserSessions(context.Context, string) error {
+	a.revokedSessions++
+	return nil
+}
+
+func (a *account) Hash(password string) (string, error) {
+	return "hashed:" + password, nil
+}
+
+func (a *account) Send(_ context.Context, message *mailer.Message) error {
+	a.mails = append(a.mails, message)
+	return nil
+}
+
+var resetLinkPattern = regexp.MustCompile(`https://\S+`)
+
+// resetToken returns the token of the reset link in the last mail.
+func (a *account) resetToken(t *testing.T) string {
+	t.Helper()
+
+	if len(a.mails) == 0 {
+		t.Fatal("no reset link was mailed")
+	}
+
+	link, err := url.Parse(resetLinkPattern.FindString(a.mails[len(a.mails)-1].Body))
+	if err != nil {
+		t.Fatalf("parsing reset link: %v", err)
+	}
+
+	return link.Query().Get(resetTokenParam)
+}
+
+func newTestUsecase(tokenTTL time.Duration) (*PasswordUsecase, *account, *resetTokens) {
+	user := &account{user: &domain.User{ID: "user", Name: "User", Email: "user@example.com", Password: "hashed:old"}}
+	tokens := &resetTokens{}
+
+	return NewPasswordUsecase(&PasswordUsecaseDeps{
+		UserTokenRepository: tokens,
+		UserRepository:      user,
+		SessionRevoker:      user,
+		PasswordHasher:      user,
+		Mailer:              user,
+		TokenTTL:            tokenTTL,
+		ResetURL:            "https://chat.example/reset",
+	}), user, tokens
+}
+
+func TestResetPassword(t *testing.T) {
+	usecase, user, tokens := newTestUsecase(time.Hour)
+	ctx := context.Background()
+
+	usecase.forgotPassword(ctx, "user@example.com")
+	rawToken := user.resetToken(t)
+
+	if err := usecase.ResetPassword(ctx, rawToken, "new password"); err != nil {
+		t.Fatalf("reset password: %v", err)
+	}
+
+	if user.user.Password != "hashed:new password" {
+		t.Fatalf("got password %q, want the hash of the new password", user.user.Password)
+	}
+
+	if user.revokedSessions != 1 {
+		t.Fatalf("revoked sessions %d times, want once", user.revokedSessions)
+	}
+
+	if len(tokens.tokens) != 0 {
+		t.Fatalf("%d reset tokens are left", len(tokens.tokens))
+	}
+
+	if err := usecase.ResetPassword(ctx, rawToken, "another password"); !errors.Is(err, apperror.ErrorToken) {
+		t.Fatalf("second reset: got %v, want %v", err, apperror.ErrorToken)
+	}
+}
+
+func TestResetPasswordRejectsTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		tokenTTL time.Duration
+		rawToken string
+		resend   bool
+	}{
+		{name: "unknown token", tokenTTL: time.Hour, rawToken: "unknown"},
+		{name: "expired token", tokenTTL: -time.Minute},
+		{name: "token replaced by a newer request", tokenTTL: time.Hour, resend: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase, user, _ := newTestUsecase(tt.tokenTTL)
+			ctx := context.Background()
+
+			usecase.forgotPassword(ctx, "user@example.com")
+
+			rawToken := user.resetToken(t)
+			if tt.rawToken != "" {
+				rawToken = tt.rawToken
+			}
+
+			if tt.resend {
+				usecase.forgotPassword(ctx, "user@example.com")
+			}
+
+			if err := usecase.ResetPassword(ctx, rawToken, "new password"); !errors.Is(err, apperror.ErrorToken) {
+				t.Fatalf("got %v, want %v", err, apperror.ErrorToken)
+			}
+
+			if user.user.Password != "hashed:old" || user.revokedSessions != 0 {
+				t.Fatal("rejected token changed the account")
+			}
+		})
+	}
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	usecase, user, tokens := newTestUsecase(time.Hour)
+
+	usecase.forgotPassword(context.Background(), "unknown@example.com")
+
+	if len(user.mails) != 0 || len(tokens.tokens) != 0 {
+		t.Fatal("a reset token was issued for an unknown email")
+	}
+}
@@ -0,0 +1,167 @@
+package usecase_room
+
+import (
+	"context"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/internal/utils"
+)
+
+type RoomRepository interface {
+	CreateRoom(ctx context.Context, room *domain.Room) (string, error)
+	GetRoom(ctx context.Context, id string) (*domain.Room, error)
+	GetRooms(ctx context.Context, filter *domain.RoomFilter) ([]domain.Room, error)
+	RenameRoom(ctx context.Context, id, name, topic string) error
+	ArchiveRoom(ctx context.Context, id string, archivedAt time.Time) error
+	DeleteRoom(ctx context.Context, id string) error
+}
+
+type MessageRemover interface {
+	DeleteRoomMessages(ctx context.Context, roomID string) error
+}
+
+type RoomUsecaseDeps struct {
+	RoomRepository RoomRepository
+	MessageRemover MessageRemover
+}
+
+type RoomUsecase struct {
+	roomRepository RoomRepository
+	messageRemover MessageRemover
+}
+
+func NewRoomUsecase(deps *RoomUsecaseDeps) *RoomUsecase {
+	return &RoomUsecase{
+		roomRepository: deps.RoomRepository,
+		messageRemover: deps.MessageRemover,
+	}
+}
+
+func (roomUsecase *RoomUsecase) CreateRoom(ctx context.Context, principal *domain.Principal, room *domain.Room) (string, error) {
+	room.OwnerID = principal.UserID
+	room.Members = uniqueMembers(principal.UserID, room.Members)
+	room.CreatedAt = time.Now()
+
+	if room.Type == domain.RoomTypeDirect && len(room.Members) != 2 {
+		return utils.EmptyString, apperror.NewAppError(apperror.ErrorValidatePayload, "direct room must have exactly one other member")
+	}
+
+	id, err := roomUsecase.roomRepository.CreateRoom(ctx, room)
+	if err != nil {
+		return utils.EmptyString, err
+	}
+
+	return id, nil
+}
+
+func (roomUsecase *RoomUsecase) GetRoom(ctx context.Context, principal *domain.Principal, id string) (*domain.Room, error) {
+	room, err := roomUsecase.roomRepository.GetRoom(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canView(principal, room) {
+		return nil, apperror.NewAppError(apperror.ErrorForbidden, "caller is not a member of this room")
+	}
+
+	return room, nil
+}
+
+func (roomUsecase *RoomUsecase) GetRooms(ctx context.Context, principal *domain.Principal, includeArchived bool) ([]domain.Room, error) {
+	filter := &domain.RoomFilter{
+		IncludeArchived: includeArchived,
+	}
+
+	if !principal.IsAdmin() {
+		filter.MemberID = principal.UserID
+	}
+
+	rooms, err := roomUsecase.roomRepository.GetRooms(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return rooms, nil
+}
+
+func (roomUsecase *RoomUsecase) RenameRoom(ctx context.Context, principal *domain.Principal, id, name, topic string) error {
+	room, err := roomUsecase.getManagedRoom(ctx, principal, id)
+	if err != nil {
+		return err
+	}
+
+	if room.IsArchived() {
+		return apperror.NewAppError(apperror.ErrorRoomArchived, "archived room can not be renamed")
+	}
+
+	return roomUsecase.roomRepository.RenameRoom(ctx, id, name, topic)
+}
+
+func (roomUsecase *RoomUsecase) ArchiveRoom(ctx context.Context, principal *domain.Principal, id string) error {
+	room, err := roomUsecase.getManagedRoom(ctx, principal, id)
+	if err != nil {
+		return err
+	}
+
+	if room.IsArchived() {
+		return nil
+	}
+
+	return roomUsecase.roomRepository.ArchiveRoom(ctx, id, time.Now())
+}
+
+// DeleteRoom removes the room together with its messages. The room is archived
+// first, so that no message can be sent while the messages are removed, and a
+// failed step leaves an archived room that can be deleted again.
+func (roomUsecase *RoomUsecase) DeleteRoom(ctx context.Context, principal *domain.Principal, id string) error {
+	room, err := roomUsecase.getManagedRoom(ctx, principal, id)
+	if err != nil {
+		return err
+	}
+
+	if !room.IsArchived() {
+		if err = roomUsecase.roomRepository.ArchiveRoom(ctx, id, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	if err = roomUsecase.messageRemover.DeleteRoomMessages(ctx, id); err != nil {
+		return err
+	}
+
+	return roomUsecase.roomRepository.DeleteRoom(ctx, id)
+}
+
+func (roomUsecase *RoomUsecase) getManagedRoom(ctx context.Context, principal *domain.Principal, id string) (*domain.Room, error) {
+	room, err := roomUsecase.roomRepository.GetRoom(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !principal.IsAdmin() && room.OwnerID != principal.UserID {
+		return nil, apperror.NewAppError(apperror.ErrorForbidden, "only the room owner can manage this room")
+	}
+
+	return room, nil
+}
+
+func canView(principal *domain.Principal, room *domain.Room) bool {
+	return room.Type == domain.RoomTypePublic || principal.IsAdmin() || room.HasMember(principal.UserID)
+}
+
+func uniqueMembers(ownerID string, members []string) []string {
+	seen := map[string]struct{}{ownerID: {}}
+	result := []string{ownerID}
+
+	for _, member := range members {
+		if _, ok := seen[member]; ok {
+			continue
+		}
+		seen[member] = struct{}{}
+		result = append(result, member)
+	}
+
+	return result
+}
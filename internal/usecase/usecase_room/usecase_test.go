@@ -0,0 +1,207 @@
+package usecase_room
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+)
+
+// roomStore records the writes of the usecase in order.
+type roomStore struct {
+	rooms map[string]*domain.Room
+	calls []string
+}
+
+func (s *roomStore) CreateRoom(_ context.Context, room *domain.Room) (string, error) {
+	room.ID = "room-created"
+	s.rooms[room.ID] = room
+
+	return room.ID, nil
+}
+
+func (s *roomStore) GetRoom(_ context.Context, id string) (*domain.Room, error) {
+	room, ok := s.rooms[id]
+	if !ok {
+		return nil, apperror.NewAppError(apperror.ErrorRoomNotFound, "room with this id does not exist")
+	}
+
+	found := *room
+	return &found, nil
+}
+
+func (s *roomStore) GetRooms(context.Context, *domain.RoomFilter) ([]domain.Room, error) {
+	return nil, nil
+}
+
+func (s *roomStore) RenameRoom(_ context.Context, id, name, topic string) error {
+	s.calls = append(s.calls, "rename "+id)
+	return nil
+}
+
+func (s *roomStore) ArchiveRoom(_ context.Context, id string, archivedAt time.Time) error {
+	s.calls = append(s.calls, "archive "+id)
+	s.rooms[id].ArchivedAt = &archivedAt
+
+	return nil
+}
+
+func (s *roomStore) DeleteRoom(_ context.Context, id string) error {
+	s.calls = append(s.calls, "delete "+id)
+	delete(s.rooms, id)
+
+	return nil
+}
+
+func (s *roomStore) DeleteRoomMessages(_ context.Context, roomID string) error {
+	s.calls = append(s.calls, "delete messages "+roomID)
+	return nil
+}
+
+func newTestUsecase(rooms ...*domain.Room) (*RoomUsecase, *roomStore) {
+	store := &roomStore{rooms: make(map[string]*domain.Room)}
+	for _, room := range rooms {
+		store.rooms[room.ID] = room
+	}
+
+	return NewRoomUsecase(&RoomUsecaseDeps{RoomRepository: store, MessageRemover: store}), store
+}
+
+var (
+	owner  = &domain.Principal{UserID: "owner", Role: domain.RoleUser}
+	member = &domain.Principal{UserID: "member", Role: domain.RoleUser}
+	other  = &domain.Principal{UserID: "other", Role: domain.RoleUser}
+	admin  = &domain.Principal{UserID: "admin", Role: domain.RoleAdmin}
+)
+
+func TestCreateRoom(t *testing.T) {
+	tests := []struct {
+		name        string
+		room        *domain.Room
+		wantMembers []string
+		wantErr     error
+	}{
+		{
+			name:        "owner becomes the first member once",
+			room:        &domain.Room{Type: domain.RoomTypePrivate, Members: []string{"member", "owner", "member"}},
+			wantMembers: []string{"owner", "member"},
+		},
+		{
+			name:        "direct room with one other member",
+			room:        &domain.Room{Type: domain.RoomTypeDirect, Members: []string{"member"}},
+			wantMembers: []string{"owner", "member"},
+		},
+		{
+			name:    "direct room with the owner only",
+			room:    &domain.Room{Type: domain.RoomTypeDirect},
+			wantErr: apperror.ErrorValidatePayload,
+		},
+		{
+			name:    "direct room with two other members",
+			room:    &domain.Room{Type: domain.RoomTypeDirect, Members: []string{"member", "other"}},
+			wantErr: apperror.ErrorValidatePayload,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase, _ := newTestUsecase()
+
+			_, err := usecase.CreateRoom(context.Background(), owner, tt.room)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && !reflect.DeepEqual(tt.room.Members, tt.wantMembers) {
+				t.Fatalf("got members %v, want %v", tt.room.Members, tt.wantMembers)
+			}
+		})
+	}
+}
+
+func TestGetRoomAccess(t *testing.T) {
+	tests := []struct {
+		name      string
+		roomType  string
+		principal *domain.Principal
+		wantErr   error
+	}{
+		{name: "public room for anyone", roomType: domain.RoomTypePublic, principal: other},
+		{name: "private room for a member", roomType: domain.RoomTypePrivate, principal: member},
+		{name: "private room for an admin", roomType: domain.RoomTypePrivate, principal: admin},
+		{name: "private room for a non member", roomType: domain.RoomTypePrivate, principal: other, wantErr: apperror.ErrorForbidden},
+		{name: "direct room for a non member", roomType: domain.RoomTypeDirect, principal: other, wantErr: apperror.ErrorForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase, _ := newTestUsecase(&domain.Room{ID: "room", Type: tt.roomType, OwnerID: "owner", Members: []string{"owner", "member"}})
+
+			if _, err := usecase.GetRoom(context.Background(), tt.principal, "room"); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenameArchivedRoom(t *testing.T) {
+	archivedAt := time.Now()
+	usecase, store := newTestUsecase(&domain.Room{ID: "room", OwnerID: "owner", ArchivedAt: &archivedAt})
+
+	if err := usecase.RenameRoom(context.Background(), owner, "room", "name", "topic"); !errors.Is(err, apperror.ErrorRoomArchived) {
+		t.Fatalf("got %v, want %v", err, apperror.ErrorRoomArchived)
+	}
+
+	if len(store.calls) != 0 {
+		t.Fatalf("got writes %v, want none", store.calls)
+	}
+}
+
+func TestDeleteRoom(t *testing.T) {
+	archivedAt := time.Now()
+
+	tests := []struct {
+		name      string
+		room      *domain.Room
+		principal *domain.Principal
+		wantCalls []string
+		wantErr   error
+	}{
+		{
+			name:      "owner deletes the room with its messages",
+			room:      &domain.Room{ID: "room", OwnerID: "owner"},
+			principal: owner,
+			wantCalls: []string{"archive room", "delete messages room", "delete room"},
+		},
+		{
+			name:      "archived room is not archived again",
+			room:      &domain.Room{ID: "room", OwnerID: "owner", ArchivedAt: &archivedAt},
+			principal: admin,
+			wantCalls: []string{"delete messages room", "delete room"},
+		},
+		{
+			name:      "member can not delete the room",
+			room:      &domain.Room{ID: "room", OwnerID: "owner", Members: []string{"owner", "member"}},
+			principal: member,
+			wantErr:   apperror.ErrorForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase, store := newTestUsecase(tt.room)
+
+			if err := usecase.DeleteRoom(context.Background(), tt.principal, "room"); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(store.calls, tt.wantCalls) {
+				t.Fatalf("got writes %v, want %v", store.calls, tt.wantCalls)
+			}
+		})
+	}
+}
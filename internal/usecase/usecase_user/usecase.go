@@ -4,71 +4,358 @@ import (
 	"context"
 	"time"
 
+	"github.com/Meystergod/gochat/internal/apperror"
 	"github.com/Meystergod/gochat/internal/domain"
 	"github.com/Meystergod/gochat/internal/utils"
+	"github.com/Meystergod/gochat/pkg/tracing"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
 )
 
+var tracer = otel.Tracer("github.com/Meystergod/gochat/internal/usecase/usecase_user")
+
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *domain.User) (string, error)
 	GetUser(ctx context.Context, id string) (*domain.User, error)
-	GetAllUsers(ctx context.Context) (*[]domain.User, error)
-	UpdateUser(ctx context.Context, user *domain.User) error
-	DeleteUser(ctx context.Context, id string) error
+	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	GetUsers(ctx context.Context, query *domain.UserQuery) (*domain.UserPage, error)
+	UpdateUser(ctx context.Context, user *domain.User, ifMatch []int64) (*domain.User, error)
+	PatchUser(ctx context.Context, id string, patch *domain.UserPatch, ifMatch []int64) (*domain.User, error)
+	UpdateUserPassword(ctx context.Context, id string, passwordHash string) error
+	DeleteUser(ctx context.Context, id string, ifMatch []int64, deletedAt time.Time) error
+	RestoreUser(ctx context.Context, id string) (*domain.User, error)
+}
+
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Compare(hash, password string) (bool, error)
+	NeedsRehash(hash string) bool
+}
+
+type VerificationSender interface {
+	SendVerification(ctx context.Context, user *domain.User) error
+}
+
+type SessionRevoker interface {
+	RevokeUserSessions(ctx context.Context, userID string) error
+}
+
+type MetricsRecorder interface {
+	RecordSignup()
+}
+
+type UserUsecaseDeps struct {
+	UserRepository     UserRepository
+	PasswordHasher     PasswordHasher
+	VerificationSender VerificationSender
+	SessionRevoker     SessionRevoker
+	MetricsRecorder    MetricsRecorder
 }
 
 type UserUsecase struct {
-	userRepository UserRepository
+	userRepository     UserRepository
+	passwordHasher     PasswordHasher
+	verificationSender VerificationSender
+	sessionRevoker     SessionRevoker
+	metricsRecorder    MetricsRecorder
+
+	// dummyPasswordHash is compared against when no user has the email, so
+	// that a login for an unknown email takes as long as one with a wrong
+	// password. It is hashed with the configured hasher to match its cost.
+	dummyPasswordHash string
 }
 
-func NewUserUsecase(userRepository UserRepository) *UserUsecase {
-	return &UserUsecase{
-		userRepository: userRepository,
+func NewUserUsecase(deps *UserUsecaseDeps) (*UserUsecase, error) {
+	dummyPasswordHash, err := deps.PasswordHasher.Hash("dummy password")
+	if err != nil {
+		return nil, errors.Wrap(err, "hashing dummy password")
 	}
+
+	return &UserUsecase{
+		userRepository:     deps.UserRepository,
+		passwordHasher:     deps.PasswordHasher,
+		verificationSender: deps.VerificationSender,
+		sessionRevoker:     deps.SessionRevoker,
+		metricsRecorder:    deps.MetricsRecorder,
+		dummyPasswordHash:  dummyPasswordHash,
+	}, nil
 }
 
+// Signup registers an unverified account and mails it a verification link.
 func (userUsecase *UserUsecase) Signup(ctx context.Context, user *domain.User) (string, error) {
+	ctx, span := tracer.Start(ctx, "UserUsecase.Signup")
+	defer span.End()
+
+	passwordHash, err := userUsecase.passwordHasher.Hash(user.Password)
+	if err != nil {
+		return utils.EmptyString, tracing.Error(span, apperror.NewAppError(apperror.ErrorHashPassword, err.Error()))
+	}
+
+	user.Password = passwordHash
+	user.Role = domain.RoleUser
 	user.RegisteredAt = time.Now()
 
 	id, err := userUsecase.userRepository.CreateUser(ctx, user)
 	if err != nil {
-		return utils.EmptyString, err
+		return utils.EmptyString, tracing.Error(span, err)
 	}
 
+	user.ID = id
+
+	userUsecase.metricsRecorder.RecordSignup()
+
+	userUsecase.sendVerification(ctx, user)
+
 	return id, nil
 }
 
+// VerifyCredentials checks the password of the user with the given email and
+// transparently upgrades the stored hash when the configured cost has changed.
+func (userUsecase *UserUsecase) VerifyCredentials(ctx context.Context, email, password string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserUsecase.VerifyCredentials")
+	defer span.End()
+
+	user, err := userUsecase.userRepository.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, apperror.ErrorNotFound) {
+			_, _ = userUsecase.passwordHasher.Compare(userUsecase.dummyPasswordHash, password)
+
+			return nil, tracing.Error(span, apperror.NewAppError(apperror.ErrorCredentials, "wrong email or password"))
+		}
+		return nil, tracing.Error(span, err)
+	}
+
+	ok, err := userUsecase.passwordHasher.Compare(user.Password, password)
+	if err != nil {
+		// a stored hash that cannot be parsed is a broken account, not a server
+		// error, and its details must not reach the client
+		zerolog.Ctx(ctx).Error().Err(err).Str("user_id", user.ID).Msg("compare password hash")
+
+		return nil, tracing.Error(span, apperror.NewAppError(apperror.ErrorCredentials, "wrong email or password"))
+	}
+	if !ok {
+		return nil, tracing.Error(span, apperror.NewAppError(apperror.ErrorCredentials, "wrong email or password"))
+	}
+
+	if userUsecase.passwordHasher.NeedsRehash(user.Password) {
+		userUsecase.rehashPassword(ctx, user, password)
+	}
+
+	return user, nil
+}
+
 func (userUsecase *UserUsecase) GetUserInfo(ctx context.Context, id string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserUsecase.GetUserInfo")
+	defer span.End()
+
 	user, err := userUsecase.userRepository.GetUser(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, tracing.Error(span, err)
 	}
 
 	return user, nil
 }
 
-func (userUsecase *UserUsecase) GetAllUsersInfo(ctx context.Context) (*[]domain.User, error) {
-	users, err := userUsecase.userRepository.GetAllUsers(ctx)
+func (userUsecase *UserUsecase) GetUsersInfo(ctx context.Context, query *domain.UserQuery) (*domain.UserPage, error) {
+	ctx, span := tracer.Start(ctx, "UserUsecase.GetUsersInfo")
+	defer span.End()
+
+	switch query.Sort {
+	case "":
+		query.Sort = domain.UserSortID
+	case domain.UserSortID, domain.UserSortName, domain.UserSortEmail, domain.UserSortRegisteredAt:
+	default:
+		return nil, tracing.Error(span, apperror.NewAppError(apperror.ErrorGetUrlParams, "sort must be one of id, name, email, registered_at"))
+	}
+
+	switch {
+	case query.Limit <= 0:
+		query.Limit = DefaultPageLimit
+	case query.Limit > MaxPageLimit:
+		query.Limit = MaxPageLimit
+	}
+
+	switch {
+	case query.Cursor != "":
+		query.Page = 0
+	case query.Page < 1:
+		query.Page = 1
+	}
+
+	if query.RegisteredFrom != nil && query.RegisteredTo != nil && query.RegisteredFrom.After(*query.RegisteredTo) {
+		return nil, tracing.Error(span, apperror.NewAppError(apperror.ErrorGetUrlParams, "registered_from must not be after registered_to"))
+	}
+
+	page, err := userUsecase.userRepository.GetUsers(ctx, query)
 	if err != nil {
-		return nil, err
+		return nil, tracing.Error(span, err)
 	}
 
-	return users, nil
+	return page, nil
 }
 
-func (userUsecase *UserUsecase) UpdateUserInfo(ctx context.Context, user *domain.User) error {
-	err := userUsecase.userRepository.UpdateUser(ctx, user)
+// UpdateUserInfo replaces the user and returns the stored result. A non-empty
+// ifMatch makes the write conditional on the current version of the user.
+// The password is always rewritten, so every session of the user is revoked.
+// A new email address has to be verified again.
+func (userUsecase *UserUsecase) UpdateUserInfo(ctx context.Context, user *domain.User, ifMatch []int64) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserUsecase.UpdateUserInfo")
+	defer span.End()
+
+	currentUser, err := userUsecase.userRepository.GetUser(ctx, user.ID)
 	if err != nil {
-		return err
+		return nil, tracing.Error(span, err)
 	}
 
-	return nil
+	passwordHash, err := userUsecase.passwordHasher.Hash(user.Password)
+	if err != nil {
+		return nil, tracing.Error(span, apperror.NewAppError(apperror.ErrorHashPassword, err.Error()))
+	}
+
+	user.Password = passwordHash
+
+	updatedUser, err := userUsecase.userRepository.UpdateUser(ctx, user, ifMatch)
+	if err != nil {
+		return nil, tracing.Error(span, err)
+	}
+
+	if err = userUsecase.sessionRevoker.RevokeUserSessions(ctx, updatedUser.ID); err != nil {
+		return nil, tracing.Error(span, err)
+	}
+
+	if updatedUser.Email != currentUser.Email {
+		userUsecase.sendVerification(ctx, updatedUser)
+	}
+
+	return updatedUser, nil
 }
 
-func (userUsecase *UserUsecase) DeleteUserAccount(ctx context.Context, id string) error {
-	err := userUsecase.userRepository.DeleteUser(ctx, id)
+// PatchUserInfo applies a partial update and returns the resulting user. An
+// empty patch leaves the user untouched. Changing the password revokes every
+// session of the user, and changing the email asks for it to be verified
+// again.
+func (userUsecase *UserUsecase) PatchUserInfo(ctx context.Context, id string, patch *domain.UserPatch, ifMatch []int64) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserUsecase.PatchUserInfo")
+	defer span.End()
+
+	if patch.IsEmpty() {
+		user, err := userUsecase.GetUserInfo(ctx, id)
+		if err != nil {
+			return nil, tracing.Error(span, err)
+		}
+
+		if !versionMatches(user.Version, ifMatch) {
+			return nil, tracing.Error(span, apperror.NewAppError(apperror.ErrorUserVersionMismatch, "user has been modified since it was read"))
+		}
+
+		return user, nil
+	}
+
+	var previousEmail string
+
+	if patch.Email != nil {
+		currentUser, err := userUsecase.userRepository.GetUser(ctx, id)
+		if err != nil {
+			return nil, tracing.Error(span, err)
+		}
+
+		previousEmail = currentUser.Email
+	}
+
+	if patch.Password != nil {
+		passwordHash, err := userUsecase.passwordHasher.Hash(*patch.Password)
+		if err != nil {
+			return nil, tracing.Error(span, apperror.NewAppError(apperror.ErrorHashPassword, err.Error()))
+		}
+
+		patch.Password = &passwordHash
+	}
+
+	user, err := userUsecase.userRepository.PatchUser(ctx, id, patch, ifMatch)
 	if err != nil {
-		return err
+		return nil, tracing.Error(span, err)
+	}
+
+	if patch.Password != nil {
+		if err = userUsecase.sessionRevoker.RevokeUserSessions(ctx, id); err != nil {
+			return nil, tracing.Error(span, err)
+		}
+	}
+
+	if patch.Email != nil && user.Email != previousEmail {
+		userUsecase.sendVerification(ctx, user)
+	}
+
+	return user, nil
+}
+
+func (userUsecase *UserUsecase) DeleteUserAccount(ctx context.Context, id string, ifMatch []int64) error {
+	ctx, span := tracer.Start(ctx, "UserUsecase.DeleteUserAccount")
+	defer span.End()
+
+	err := userUsecase.userRepository.DeleteUser(ctx, id, ifMatch, time.Now())
+	if err != nil {
+		return tracing.Error(span, err)
 	}
 
 	return nil
 }
+
+func (userUsecase *UserUsecase) RestoreUserAccount(ctx context.Context, id string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserUsecase.RestoreUserAccount")
+	defer span.End()
+
+	user, err := userUsecase.userRepository.RestoreUser(ctx, id)
+	if err != nil {
+		return nil, tracing.Error(span, err)
+	}
+
+	return user, nil
+}
+
+// sendVerification mails a verification link for an account that has already
+// been written, so a failed delivery is only logged and the user can ask for
+// the verification email to be resent.
+func (userUsecase *UserUsecase) sendVerification(ctx context.Context, user *domain.User) {
+	if err := userUsecase.verificationSender.SendVerification(ctx, user); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("user_id", user.ID).Msg("send verification email")
+	}
+}
+
+func (userUsecase *UserUsecase) rehashPassword(ctx context.Context, user *domain.User, password string) {
+	logger := zerolog.Ctx(ctx)
+
+	passwordHash, err := userUsecase.passwordHasher.Hash(password)
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", user.ID).Msg("rehash password")
+		return
+	}
+
+	if err = userUsecase.userRepository.UpdateUserPassword(ctx, user.ID, passwordHash); err != nil {
+		logger.Error().Err(err).Str("user_id", user.ID).Msg("rehash password")
+		return
+	}
+
+	user.Password = passwordHash
+}
+
+func versionMatches(version int64, ifMatch []int64) bool {
+	if len(ifMatch) == 0 {
+		return true
+	}
+
+	for _, v := range ifMatch {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
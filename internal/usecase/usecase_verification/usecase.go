@@ -0,0 +1,182 @@
+package usecase_verification
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/pkg/mailer"
+	"github.com/Meystergod/gochat/pkg/token"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const verificationTokenSize = 32
+
+const verificationTokenParam = "token"
+
+// sendTimeout bounds looking up the user and mailing the verification link
+// when it is resent after the request has been answered.
+const sendTimeout = 30 * time.Second
+
+type UserTokenRepository interface {
+	CreateUserToken(ctx context.Context, token *domain.UserToken) (string, error)
+	UseUserToken(ctx context.Context, purpose, tokenHash string, usedAt time.Time) (*domain.UserToken, error)
+	DeleteUserTokens(ctx context.Context, userID, purpose string) error
+}
+
+type UserRepository interface {
+	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	MarkUserVerified(ctx context.Context, id string, verifiedAt time.Time) error
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message *mailer.Message) error
+}
+
+type VerificationUsecaseDeps struct {
+	UserTokenRepository UserTokenRepository
+	UserRepository      UserRepository
+	Mailer              Mailer
+	TokenTTL            time.Duration
+	VerifyURL           string
+}
+
+type VerificationUsecase struct {
+	userTokenRepository UserTokenRepository
+	userRepository      UserRepository
+	mailer              Mailer
+	tokenTTL            time.Duration
+	verifyURL           string
+}
+
+func NewVerificationUsecase(deps *VerificationUsecaseDeps) *VerificationUsecase {
+	return &VerificationUsecase{
+		userTokenRepository: deps.UserTokenRepository,
+		userRepository:      deps.UserRepository,
+		mailer:              deps.Mailer,
+		tokenTTL:            deps.TokenTTL,
+		verifyURL:           deps.VerifyURL,
+	}
+}
+
+// SendVerification issues a fresh verification token for the user, replacing
+// any earlier one, and mails the verification link to the user's address.
+func (verificationUsecase *VerificationUsecase) SendVerification(ctx context.Context, user *domain.User) error {
+	rawToken, err := token.NewRandomToken(verificationTokenSize)
+	if err != nil {
+		return apperror.NewAppError(apperror.ErrorGenerateToken, err.Error())
+	}
+
+	err = verificationUsecase.userTokenRepository.DeleteUserTokens(ctx, user.ID, domain.TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	_, err = verificationUsecase.userTokenRepository.CreateUserToken(ctx, &domain.UserToken{
+		UserID:    user.ID,
+		Purpose:   domain.TokenPurposeEmailVerification,
+		TokenHash: token.HashToken(rawToken),
+		CreatedAt: now,
+		ExpiresAt: now.Add(verificationUsecase.tokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := verificationUsecase.verificationLink(rawToken)
+	if err != nil {
+		return apperror.NewAppError(apperror.ErrorInternal, err.Error())
+	}
+
+	message := &mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\r\n\r\nconfirm your email address by opening the link below:\r\n\r\n%s\r\n\r\nThe link expires in %s.\r\n",
+			user.Name, link, verificationUsecase.tokenTTL,
+		),
+	}
+
+	if err = verificationUsecase.mailer.Send(ctx, message); err != nil {
+		return apperror.NewAppError(apperror.ErrorInternal, errors.Wrap(err, "failed to send verification email").Error())
+	}
+
+	return nil
+}
+
+// ResendVerification mails a new verification link to an unverified account.
+// The lookup and the mail happen in the background, detached from the request,
+// so that the endpoint answers unknown, verified and unverified addresses alike
+// and in the same time; failures are only logged.
+func (verificationUsecase *VerificationUsecase) ResendVerification(ctx context.Context, email string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sendTimeout)
+		defer cancel()
+
+		verificationUsecase.resendVerification(ctx, email)
+	}()
+}
+
+// Verify redeems a verification token and marks its owner verified. It
+// returns the id of the verified user.
+func (verificationUsecase *VerificationUsecase) Verify(ctx context.Context, rawToken string) (string, error) {
+	logger := zerolog.Ctx(ctx)
+
+	userToken, err := verificationUsecase.userTokenRepository.UseUserToken(
+		ctx, domain.TokenPurposeEmailVerification, token.HashToken(rawToken), time.Now(),
+	)
+	if err != nil {
+		if errors.Is(err, apperror.ErrorNotFound) {
+			return "", apperror.NewAppError(apperror.ErrorToken, "verification token is not valid")
+		}
+		return "", err
+	}
+
+	if err = verificationUsecase.userRepository.MarkUserVerified(ctx, userToken.UserID, time.Now()); err != nil {
+		return "", err
+	}
+
+	logger.Info().Str("user_id", userToken.UserID).Msg("verified user email")
+
+	return userToken.UserID, nil
+}
+
+func (verificationUsecase *VerificationUsecase) resendVerification(ctx context.Context, email string) {
+	logger := zerolog.Ctx(ctx)
+
+	user, err := verificationUsecase.userRepository.GetUserByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, apperror.ErrorNotFound) {
+			logger.Error().Err(err).Msg("look up user for verification resend")
+		}
+		return
+	}
+
+	if user.IsVerified() {
+		return
+	}
+
+	if err = verificationUsecase.SendVerification(ctx, user); err != nil {
+		logger.Error().Err(err).Str("user_id", user.ID).Msg("resend verification email")
+	}
+}
+
+func (verificationUsecase *VerificationUsecase) verificationLink(rawToken string) (string, error) {
+	link, err := url.Parse(verificationUsecase.verifyURL)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing verification url")
+	}
+
+	query := link.Query()
+	query.Set(verificationTokenParam, rawToken)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
@@ -0,0 +1,219 @@
+package usecase_verification
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Meystergod/gochat/internal/apperror"
+	"github.com/Meystergod/gochat/internal/domain"
+	"github.com/Meystergod/gochat/pkg/mailer"
+)
+
+// tokenStore consumes tokens the way the mongo repository does: a token is
+// redeemable once, before it expires.
+type tokenStore struct {
+	tokens []*domain.UserToken
+}
+
+func (s *tokenStore) CreateUserToken(_ context.Context, userToken *domain.UserToken) (string, error) {
+	s.tokens = append(s.tokens, userToken)
+	return "token", nil
+}
+
+func (s *tokenStore) UseUserToken(_ context.Context, purpose, tokenHash string, usedAt time.Time) (*domain.UserToken, error) {
+	for _, userToken := range s.tokens {
+		if userToken.Purpose == purpose && userToken.TokenHash == tokenHash && userToken.UsedAt == nil && userToken.ExpiresAt.After(usedAt) {
+			userToken.UsedAt = &usedAt
+			return userToken, nil
+		}
+	}
+
+	return nil, apperror.NewAppError(apperror.ErrorNotFound, "user token does not exist, expired or was already used")
+}
+
+func (s *tokenStore) DeleteUserTokens(_ context.Context, userID, purpose string) error {
+	kept := s.tokens[:0]
+	for _, userToken := range s.tokens {
+		if userToken.UserID != userID || userToken.Purpose != purpose {
+			kept = append(kept, userToken)
+		}
+	}
+
+	s.tokens = kept
+
+	return nil
+}
+
+type userStore struct {
+	users map[string]*domain.User
+}
+
+func (s *userStore) GetUserByEmail(_ context.Context, email string) (*domain.User, error) {
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return nil, apperror.NewAppError(apperror.ErrorUserNotFound, "user with this email does not exist")
+}
+
+func (s *userStore) MarkUserVerified(_ context.Context, id string, verifiedAt time.Time) error {
+	s.users[id].VerifiedAt = &verifiedAt
+	return nil
+}
+
+type outbox struct {
+	messages []*mailer.Message
+}
+
+func (o *outbox) Send(_ context.Context, message *mailer.Message) error {
+	o.messages = append(o.messages, message)
+	return nil
+}
+
+var linkPattern = regexp.MustCompile(`https://\S+`)
+
+// mailedToken returns the token of the link in the n-th mailed message.
+func (o *outbox) mailedToken(t *testing.T, n int) string {
+	t.Helper()
+
+	if len(o.messages) <= n {
+		t.Fatalf("got %d messages, want more than %d", len(o.messages), n)
+	}
+
+	link, err := url.Parse(linkPattern.FindString(o.messages[n].Body))
+	if err != nil {
+		t.Fatalf("parsing mailed link: %v", err)
+	}
+
+	return link.Query().Get(verificationTokenParam)
+}
+
+type testUsecase struct {
+	*VerificationUsecase
+	users  *userStore
+	outbox *outbox
+}
+
+func newTestUsecase(tokenTTL time.Duration) *testUsecase {
+	verifiedAt := time.Now()
+
+	users := &userStore{users: map[string]*domain.User{
+		"new":      {ID: "new", Name: "New", Email: "new@example.com"},
+		"verified": {ID: "verified", Name: "Verified", Email: "verified@example.com", VerifiedAt: &verifiedAt},
+	}}
+	sent := &outbox{}
+
+	return &testUsecase{
+		VerificationUsecase: NewVerificationUsecase(&VerificationUsecaseDeps{
+			UserTokenRepository: &tokenStore{},
+			UserRepository:      users,
+			Mailer:              sent,
+			TokenTTL:            tokenTTL,
+			VerifyURL:           "https://chat.example/verify?lang=en",
+		}),
+		users:  users,
+		outbox: sent,
+	}
+}
+
+func TestVerifyMailedToken(t *testing.T) {
+	usecase := newTestUsecase(time.Hour)
+	ctx := context.Background()
+
+	if err := usecase.SendVerification(ctx, usecase.users.users["new"]); err != nil {
+		t.Fatalf("send verification: %v", err)
+	}
+
+	message := usecase.outbox.messages[0]
+	if message.To != "new@example.com" {
+		t.Fatalf("mailed %s, want new@example.com", message.To)
+	}
+
+	rawToken := usecase.outbox.mailedToken(t, 0)
+
+	userID, err := usecase.Verify(ctx, rawToken)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	if userID != "new" || !usecase.users.users["new"].IsVerified() {
+		t.Fatalf("verified %q, want the new user marked verified", userID)
+	}
+
+	if _, err = usecase.Verify(ctx, rawToken); !errors.Is(err, apperror.ErrorToken) {
+		t.Fatalf("second verify: got %v, want %v", err, apperror.ErrorToken)
+	}
+}
+
+func TestVerifyRejectsTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		tokenTTL time.Duration
+		rawToken string
+		resend   bool
+	}{
+		{name: "unknown token", tokenTTL: time.Hour, rawToken: "unknown"},
+		{name: "expired token", tokenTTL: -time.Minute},
+		{name: "token replaced by a resend", tokenTTL: time.Hour, resend: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := newTestUsecase(tt.tokenTTL)
+			ctx := context.Background()
+
+			if err := usecase.SendVerification(ctx, usecase.users.users["new"]); err != nil {
+				t.Fatalf("send verification: %v", err)
+			}
+
+			rawToken := usecase.outbox.mailedToken(t, 0)
+			if tt.rawToken != "" {
+				rawToken = tt.rawToken
+			}
+
+			if tt.resend {
+				if err := usecase.SendVerification(ctx, usecase.users.users["new"]); err != nil {
+					t.Fatalf("resend verification: %v", err)
+				}
+			}
+
+			if _, err := usecase.Verify(ctx, rawToken); !errors.Is(err, apperror.ErrorToken) {
+				t.Fatalf("got %v, want %v", err, apperror.ErrorToken)
+			}
+
+			if usecase.users.users["new"].IsVerified() {
+				t.Fatal("user was verified")
+			}
+		})
+	}
+}
+
+func TestResendVerification(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		wantSent bool
+	}{
+		{name: "unverified account", email: "new@example.com", wantSent: true},
+		{name: "verified account", email: "verified@example.com"},
+		{name: "unknown email", email: "unknown@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := newTestUsecase(time.Hour)
+
+			usecase.resendVerification(context.Background(), tt.email)
+
+			if sent := len(usecase.outbox.messages) == 1; sent != tt.wantSent {
+				t.Fatalf("got %d messages, want sent %v", len(usecase.outbox.messages), tt.wantSent)
+			}
+		})
+	}
+}
@@ -5,5 +5,15 @@ const (
 )
 
 const (
-	CollNameUser = "users"
+	CollNameUser    = "users"
+	CollNameSession = "sessions"
+	CollNameRoom    = "rooms"
+	CollNameMessage = "messages"
+	CollNameEvent   = "events"
+	CollNameToken   = "user_tokens"
+
+	CollNameRateLimit = "rate_limits"
+
+	CollNameMigration     = "migrations"
+	CollNameMigrationLock = "migration_locks"
 )
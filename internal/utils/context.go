@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/Meystergod/gochat/internal/domain"
+)
+
+type principalContextKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, principal *domain.Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (*domain.Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(*domain.Principal)
+	return principal, ok
+}
@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"reflect"
+	"strconv"
+	"strings"
+
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 )
@@ -10,21 +14,60 @@ type Validator struct {
 }
 
 func NewValidator() echo.Validator {
-	return &Validator{validator: validator.New()}
+	v := validator.New()
+
+	// report payload field names as clients send them instead of go names
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return EmptyString
+		}
+
+		return name
+	})
+
+	// maxbytes limits the encoded length of a string, e.g. for passwords
+	// that bcrypt rejects beyond 72 bytes however few characters they have
+	_ = v.RegisterValidation("maxbytes", func(field validator.FieldLevel) bool {
+		limit, err := strconv.Atoi(field.Param())
+		if err != nil {
+			return false
+		}
+
+		return len(field.Field().String()) <= limit
+	})
+
+	return &Validator{validator: v}
 }
 
 func (v *Validator) Validate(i interface{}) error {
 	return v.validator.Struct(i)
 }
 
+// Normalizer is implemented by payloads that canonicalize their values after
+// binding, so that validation and the usecases see normalized input.
+type Normalizer interface {
+	Normalize()
+}
+
 func BindAndValidate(c echo.Context, i interface{}) error {
 	if err := c.Bind(i); err != nil {
 		return err
 	}
 
+	if normalizer, ok := i.(Normalizer); ok {
+		normalizer.Normalize()
+	}
+
 	if err := c.Validate(i); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// NormalizeEmail trims and lowercases an email address, matching the case
+// insensitive unique index on users.email.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -2,77 +2,342 @@ package client
 
 import (
 	"context"
-	"fmt"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"strconv"
 	"time"
 
+	"github.com/Meystergod/gochat/pkg/tracing"
+
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	AuthMechanismX509 = "MONGODB-X509"
+
+	WriteConcernMajority = "majority"
+
+	maxConnectBackoff  = 30 * time.Second
+	defaultPingTimeout = 30 * time.Second
 )
 
+// MongoConfig describes the connection either as a full URI or as structured
+// options. When URI is set it is used as is, and only the database name,
+// the monitors and the connect retry settings are taken from the other
+// fields.
 type MongoConfig struct {
+	URI string
+
+	// Hosts are host:port seeds. Host and Port are used when there are none.
+	Hosts        []string
 	Host         string
 	Port         string
+	ReplicaSet   string
 	DatabaseName string
-	AuthSource   string
-	Username     string
-	Password     string
-}
 
-func NewMongoConfig(authSource, username, password, host, port, db string) *MongoConfig {
-	return &MongoConfig{
-		Host:         host,
-		Port:         port,
-		DatabaseName: db,
-		AuthSource:   authSource,
-		Username:     username,
-		Password:     password,
-	}
+	AuthSource    string
+	AuthMechanism string
+	Username      string
+	Password      string
+
+	TLS       bool
+	TLSCAFile string
+	// TLSCertKeyFile is a pem file with the client certificate and its key,
+	// as used for x509 authentication.
+	TLSCertKeyFile string
+	TLSInsecure    bool
+
+	MinPoolSize            uint64
+	MaxPoolSize            uint64
+	MaxConnIdleTime        time.Duration
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+
+	ReadPreference string
+	ReadConcern    string
+	// WriteConcern is majority, a number of nodes or a tag set name.
+	WriteConcern string
+	RetryWrites  bool
+
+	// ConnectAttempts is how many times the initial ping is tried, waiting
+	// ConnectBackoff, doubled after every attempt, in between.
+	ConnectAttempts int
+	ConnectBackoff  time.Duration
+
+	CommandMonitor *event.CommandMonitor
+	PoolMonitor    *event.PoolMonitor
+	// TracerProvider instruments every command with a client span when set.
+	TracerProvider trace.TracerProvider
 }
 
 func NewMongoDatabase(ctx context.Context, cfg *MongoConfig) (*mongo.Database, error) {
-	var url string
-	var anonymous bool
-	var client *mongo.Client
+	logger := zerolog.Ctx(ctx)
+
+	clientOptions, err := mongoClientOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var commandMonitors []*event.CommandMonitor
+
+	if cfg.CommandMonitor != nil {
+		commandMonitors = append(commandMonitors, cfg.CommandMonitor)
+	}
+
+	if cfg.TracerProvider != nil {
+		commandMonitors = append(commandMonitors, tracing.NewMongoTracing(cfg.TracerProvider).CommandMonitor())
+	}
 
+	if len(commandMonitors) > 0 {
+		clientOptions.SetMonitor(chainCommandMonitors(commandMonitors...))
+	}
+
+	if cfg.PoolMonitor != nil {
+		clientOptions.SetPoolMonitor(cfg.PoolMonitor)
+	}
+
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to database")
+	}
+
+	if err = pingWithRetry(ctx, client, cfg); err != nil {
+		if disconnectErr := client.Disconnect(context.Background()); disconnectErr != nil {
+			logger.Error().Err(disconnectErr).Msg("disconnect from the mongo database")
+		}
+
+		return nil, err
+	}
+
+	logger.Info().Msg("successfully connected to the mongo database")
+
+	return client.Database(cfg.DatabaseName), nil
+}
+
+// pingWithRetry waits for the deployment to become reachable, so that the
+// application can start alongside a database that is still booting.
+func pingWithRetry(ctx context.Context, client *mongo.Client, cfg *MongoConfig) error {
 	logger := zerolog.Ctx(ctx)
 
-	if cfg.Username == "" || cfg.Password == "" {
-		anonymous = true
-		url = fmt.Sprintf("mongodb://%s:%s", cfg.Host, cfg.Port)
-	} else {
-		url = fmt.Sprintf("mongodb://%s:%s@%s:%s", cfg.Username, cfg.Password, cfg.Host, cfg.Port)
+	attempts := cfg.ConnectAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	pingTimeout := cfg.ServerSelectionTimeout
+	if pingTimeout <= 0 {
+		pingTimeout = defaultPingTimeout
+	}
+
+	backoff := cfg.ConnectBackoff
+
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err = client.Ping(pingCtx, nil)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+
+		if attempt == attempts {
+			break
+		}
+
+		logger.Warn().Err(err).Int("attempt", attempt).Dur("backoff", backoff).Msg("ping the mongo database")
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "failed to ping to database")
+		}
+
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+
+	return errors.Wrapf(err, "failed to ping to database after %d attempt(s)", attempts)
+}
+
+func mongoClientOptions(cfg *MongoConfig) (*options.ClientOptions, error) {
+	if cfg.URI != "" {
+		return options.Client().ApplyURI(cfg.URI), nil
 	}
 
-	clientOptions := options.Client().ApplyURI(url)
+	hosts := cfg.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{net.JoinHostPort(cfg.Host, cfg.Port)}
+	}
+
+	clientOptions := options.Client().
+		SetHosts(hosts).
+		SetRetryWrites(cfg.RetryWrites)
+
+	if cfg.ReplicaSet != "" {
+		clientOptions.SetReplicaSet(cfg.ReplicaSet)
+	}
 
-	if !anonymous {
+	// credentials are passed as options rather than in an url, so they need
+	// no escaping
+	switch {
+	case cfg.AuthMechanism == AuthMechanismX509:
+		clientOptions.SetAuth(options.Credential{
+			AuthMechanism: AuthMechanismX509,
+			Username:      cfg.Username,
+		})
+	case cfg.Username != "":
 		clientOptions.SetAuth(options.Credential{
-			AuthSource:  cfg.AuthSource,
-			Username:    cfg.Username,
-			Password:    cfg.Password,
-			PasswordSet: true,
+			AuthMechanism: cfg.AuthMechanism,
+			AuthSource:    cfg.AuthSource,
+			Username:      cfg.Username,
+			Password:      cfg.Password,
+			PasswordSet:   cfg.Password != "",
 		})
 	}
 
-	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
+	if cfg.TLS || cfg.TLSCAFile != "" || cfg.TLSCertKeyFile != "" {
+		tlsConfig, err := mongoTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
 
-	var connectionError error
-	var pingError error
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
 
-	client, connectionError = mongo.Connect(reqCtx, clientOptions)
-	if connectionError != nil {
-		return nil, errors.New("failed to connect to database")
+	if cfg.MinPoolSize > 0 {
+		clientOptions.SetMinPoolSize(cfg.MinPoolSize)
 	}
 
-	pingError = client.Ping(context.Background(), nil)
-	if pingError != nil {
-		return nil, errors.New("failed to ping to database")
+	if cfg.MaxPoolSize > 0 {
+		clientOptions.SetMaxPoolSize(cfg.MaxPoolSize)
 	}
 
-	logger.Info().Msg("successfully connected to the mongo database")
+	if cfg.MaxConnIdleTime > 0 {
+		clientOptions.SetMaxConnIdleTime(cfg.MaxConnIdleTime)
+	}
 
-	return client.Database(cfg.DatabaseName), nil
+	if cfg.ConnectTimeout > 0 {
+		clientOptions.SetConnectTimeout(cfg.ConnectTimeout)
+	}
+
+	if cfg.ServerSelectionTimeout > 0 {
+		clientOptions.SetServerSelectionTimeout(cfg.ServerSelectionTimeout)
+	}
+
+	if cfg.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(cfg.ReadPreference)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing read preference")
+		}
+
+		readPreference, err := readpref.New(mode)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating read preference")
+		}
+
+		clientOptions.SetReadPreference(readPreference)
+	}
+
+	if cfg.ReadConcern != "" {
+		clientOptions.SetReadConcern(readconcern.New(readconcern.Level(cfg.ReadConcern)))
+	}
+
+	if cfg.WriteConcern != "" {
+		clientOptions.SetWriteConcern(parseWriteConcern(cfg.WriteConcern))
+	}
+
+	return clientOptions, nil
+}
+
+func mongoTLSConfig(cfg *MongoConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.TLSInsecure,
+	}
+
+	if cfg.TLSCAFile != "" {
+		ca, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading tls ca file")
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("no certificates found in %s", cfg.TLSCAFile)
+		}
+	}
+
+	if cfg.TLSCertKeyFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCertKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading tls certificate key file")
+		}
+
+		certificate, err := tls.X509KeyPair(pem, pem)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing tls certificate key file")
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+func parseWriteConcern(value string) *writeconcern.WriteConcern {
+	if value == WriteConcernMajority {
+		return writeconcern.Majority()
+	}
+
+	if w, err := strconv.Atoi(value); err == nil {
+		return &writeconcern.WriteConcern{W: w}
+	}
+
+	return writeconcern.Custom(value)
+}
+
+// chainCommandMonitors fans the events out to every monitor, because the
+// driver accepts only one.
+func chainCommandMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	if len(monitors) == 1 {
+		return monitors[0]
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Started != nil {
+					monitor.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, monitor := range monitors {
+				if monitor.Succeeded != nil {
+					monitor.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Failed != nil {
+					monitor.Failed(ctx, e)
+				}
+			}
+		},
+	}
 }
@@ -0,0 +1,56 @@
+package hasher
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type BcryptHasherDeps struct {
+	Cost int
+}
+
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(deps *BcryptHasherDeps) (*BcryptHasher, error) {
+	if deps.Cost < bcrypt.MinCost || deps.Cost > bcrypt.MaxCost {
+		return nil, errors.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return &BcryptHasher{
+		cost: deps.Cost,
+	}, nil
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", errors.Wrap(err, "generating bcrypt hash")
+	}
+
+	return string(hash), nil
+}
+
+func (h *BcryptHasher) Compare(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, errors.Wrap(err, "comparing bcrypt hash")
+	}
+}
+
+// NeedsRehash reports whether the hash was produced with a cost other than the
+// configured one, so that it can be upgraded on the next successful login.
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+
+	return cost != h.cost
+}
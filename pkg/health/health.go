@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+)
+
+// Check probes a single dependency. It must honour the context deadline.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Name    string
+	Status  string
+	Latency time.Duration
+	Error   string
+}
+
+type Report struct {
+	Status string
+	Checks []CheckResult
+}
+
+func (r *Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+type HealthDeps struct {
+	Timeout time.Duration
+	Checks  []Check
+}
+
+// Health tracks the readiness of the instance. It is ready when every check
+// passes within the timeout and the instance is not draining. Draining is a
+// one-way switch flipped on shutdown, so that load balancers stop routing new
+// requests before the server stops accepting them.
+type Health struct {
+	timeout  time.Duration
+	checks   []Check
+	draining atomic.Bool
+}
+
+func NewHealth(deps *HealthDeps) *Health {
+	return &Health{
+		timeout: deps.Timeout,
+		checks:  deps.Checks,
+	}
+}
+
+func (h *Health) SetDraining() {
+	h.draining.Store(true)
+}
+
+func (h *Health) Draining() bool {
+	return h.draining.Load()
+}
+
+// Report runs every check concurrently and reports their outcome.
+func (h *Health) Report(ctx context.Context) *Report {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+
+	defer cancel()
+
+	results := make([]CheckResult, len(h.checks))
+
+	var wg sync.WaitGroup
+
+	for i, check := range h.checks {
+		wg.Add(1)
+
+		go func(i int, check Check) {
+			defer wg.Done()
+
+			started := time.Now()
+			err := check.Check(ctx)
+
+			results[i] = CheckResult{
+				Name:    check.Name,
+				Status:  StatusOK,
+				Latency: time.Since(started),
+			}
+
+			if err != nil {
+				results[i].Status = StatusFail
+				results[i].Error = err.Error()
+			}
+		}(i, check)
+	}
+
+	wg.Wait()
+
+	report := &Report{
+		Status: StatusOK,
+		Checks: results,
+	}
+
+	for _, result := range results {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	if h.Draining() {
+		report.Status = StatusDraining
+	}
+
+	return report
+}
@@ -3,6 +3,8 @@ package httpserver
 import (
 	"context"
 	"fmt"
+	"net"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -10,8 +12,14 @@ import (
 )
 
 type ServerDeps struct {
-	Host string
-	Port string
+	Host         string
+	Port         string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// TrustedProxies are the CIDR ranges of the reverse proxies whose
+	// X-Forwarded-For is believed. Without any, the client ip is the address
+	// of the connection.
+	TrustedProxies []string
 }
 
 type Server struct {
@@ -20,14 +28,23 @@ type Server struct {
 	echoServer *echo.Echo
 }
 
-func NewDefaultServer(deps *ServerDeps) *Server {
+func NewDefaultServer(deps *ServerDeps) (*Server, error) {
 	echoServer := echo.New()
 
+	ipExtractor, err := newIPExtractor(deps.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	echoServer.IPExtractor = ipExtractor
+
 	echoServer.Use(middleware.Recover())
 	echoServer.Debug = true
 	echoServer.DisableHTTP2 = true
 	echoServer.HideBanner = true
 	echoServer.HidePort = true
+	echoServer.Server.ReadTimeout = deps.ReadTimeout
+	echoServer.Server.WriteTimeout = deps.WriteTimeout
 
 	s := &Server{
 		host:       deps.Host,
@@ -37,7 +54,32 @@ func NewDefaultServer(deps *ServerDeps) *Server {
 
 	s.echoServer = echoServer
 
-	return s
+	return s, nil
+}
+
+// newIPExtractor never trusts client supplied headers by default, because
+// c.RealIP keys the rate limits and a forged header would reset them.
+func newIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+
+	for _, proxy := range trustedProxies {
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing trusted proxy %q", proxy)
+		}
+
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...), nil
 }
 
 func (s *Server) Server() *echo.Echo {
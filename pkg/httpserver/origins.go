@@ -0,0 +1,61 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// Origins is an allow-list of request origins that can be replaced while the
+// server is running. A single "*" entry allows any origin.
+type Origins struct {
+	allowed atomic.Pointer[map[string]struct{}]
+}
+
+func NewOrigins(origins []string) *Origins {
+	o := &Origins{}
+	o.Set(origins)
+
+	return o
+}
+
+func (o *Origins) Set(origins []string) {
+	allowed := make(map[string]struct{}, len(origins))
+	for _, origin := range origins {
+		allowed[origin] = struct{}{}
+	}
+
+	o.allowed.Store(&allowed)
+}
+
+func (o *Origins) Empty() bool {
+	return len(*o.allowed.Load()) == 0
+}
+
+func (o *Origins) Allowed(origin string) bool {
+	allowed := *o.allowed.Load()
+
+	if _, ok := allowed["*"]; ok {
+		return true
+	}
+
+	_, ok := allowed[origin]
+	return ok
+}
+
+// sameOrigin reports whether the Origin header of the request, if any, names
+// the host the request was sent to.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,80 @@
+package httpserver
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// SSEWriter writes a text/event-stream response. Every write pushes the
+// connection write deadline forward by writeWait, so a long-lived stream is
+// not cut off by the server wide write timeout meant for ordinary requests.
+type SSEWriter struct {
+	response   *echo.Response
+	controller *http.ResponseController
+	writeWait  time.Duration
+}
+
+func NewSSEWriter(c echo.Context, writeWait time.Duration) *SSEWriter {
+	response := c.Response()
+
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	response.Header().Set(echo.HeaderConnection, "keep-alive")
+	response.Header().Set("X-Accel-Buffering", "no")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	return &SSEWriter{
+		response:   response,
+		controller: http.NewResponseController(response),
+		writeWait:  writeWait,
+	}
+}
+
+// Event writes a single event. An empty id or event name is omitted, and
+// multi-line data is split into several data fields.
+func (w *SSEWriter) Event(id, event string, data []byte) error {
+	var buf bytes.Buffer
+
+	if id != "" {
+		fmt.Fprintf(&buf, "id: %s\n", id)
+	}
+
+	if event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", event)
+	}
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+
+	buf.WriteByte('\n')
+
+	return w.write(buf.Bytes())
+}
+
+// Comment writes a comment line, which clients ignore; it is used as a
+// keep-alive so that proxies do not close an idle stream.
+func (w *SSEWriter) Comment(text string) error {
+	return w.write([]byte(fmt.Sprintf(": %s\n\n", text)))
+}
+
+func (w *SSEWriter) write(data []byte) error {
+	err := w.controller.SetWriteDeadline(time.Now().Add(w.writeWait))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return errors.Wrap(err, "extending sse write deadline")
+	}
+
+	if _, err = w.response.Write(data); err != nil {
+		return errors.Wrap(err, "writing sse frame")
+	}
+
+	w.response.Flush()
+
+	return nil
+}
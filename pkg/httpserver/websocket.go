@@ -0,0 +1,34 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+type WebSocketDeps struct {
+	ReadBufferSize  int
+	WriteBufferSize int
+	AllowedOrigins  *Origins
+}
+
+// NewWebSocketUpgrader builds an upgrader that accepts the allowed origins.
+// While no origins are allowed only same-origin requests are accepted.
+func NewWebSocketUpgrader(deps *WebSocketDeps) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  deps.ReadBufferSize,
+		WriteBufferSize: deps.WriteBufferSize,
+		CheckOrigin: func(r *http.Request) bool {
+			if deps.AllowedOrigins.Empty() {
+				return sameOrigin(r)
+			}
+
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+
+			return deps.AllowedOrigins.Allowed(origin)
+		},
+	}
+}
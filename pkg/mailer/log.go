@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// LogMailer writes messages to a writer instead of delivering them. It is
+// meant for local development, where following the mail log is enough to
+// complete flows such as email verification.
+type LogMailer struct {
+	mu     sync.Mutex
+	writer io.Writer
+	from   string
+}
+
+func NewLogMailer(writer io.Writer, from string) *LogMailer {
+	return &LogMailer{
+		writer: writer,
+		from:   from,
+	}
+}
+
+func (m *LogMailer) Send(_ context.Context, message *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	raw := append(formatMessage(m.from, message), "\r\n\r\n"...)
+
+	if _, err := m.writer.Write(raw); err != nil {
+		return errors.Wrap(err, "writing mail to log")
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverLog  = "log"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers plain text emails. Implementations must be safe for
+// concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+var ErrUnknownDriver = errors.New("unknown mailer driver")
+
+type Deps struct {
+	Driver string
+	From   string
+
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+
+	// LogFile is where the log driver appends messages. Standard output is
+	// used when it is empty.
+	LogFile string
+}
+
+func NewMailer(deps *Deps) (Mailer, error) {
+	switch deps.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(&SMTPMailerDeps{
+			Host:     deps.SMTPHost,
+			Port:     deps.SMTPPort,
+			Username: deps.SMTPUsername,
+			Password: deps.SMTPPassword,
+			From:     deps.From,
+		})
+	case DriverLog:
+		var writer io.Writer = os.Stdout
+
+		if deps.LogFile != "" {
+			file, err := os.OpenFile(deps.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+			if err != nil {
+				return nil, errors.Wrap(err, "opening mail log file")
+			}
+
+			writer = file
+		}
+
+		return NewLogMailer(writer, deps.From), nil
+	default:
+		return nil, errors.Wrap(ErrUnknownDriver, deps.Driver)
+	}
+}
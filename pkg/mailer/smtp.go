@@ -0,0 +1,104 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type SMTPMailerDeps struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(deps *SMTPMailerDeps) (*SMTPMailer, error) {
+	if deps.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+	if deps.From == "" {
+		return nil, errors.New("sender address is required")
+	}
+
+	var auth smtp.Auth
+	if deps.Username != "" {
+		auth = smtp.PlainAuth("", deps.Username, deps.Password, deps.Host)
+	}
+
+	return &SMTPMailer{
+		addr: net.JoinHostPort(deps.Host, deps.Port),
+		auth: auth,
+		from: deps.From,
+	}, nil
+}
+
+// Send delivers the message through the smtp relay. net/smtp does not accept
+// a context, so the delivery runs in the background and Send returns early
+// when the context is done.
+func (m *SMTPMailer) Send(ctx context.Context, message *Message) error {
+	from, err := envelopeAddress(m.from)
+	if err != nil {
+		return err
+	}
+
+	to, err := envelopeAddress(message.To)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- smtp.SendMail(m.addr, m.auth, from, []string{to}, formatMessage(m.from, message))
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err = <-done:
+		if err != nil {
+			return errors.Wrap(err, "sending mail over smtp")
+		}
+
+		return nil
+	}
+}
+
+func envelopeAddress(address string) (string, error) {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing address %q", address)
+	}
+
+	return parsed.Address, nil
+}
+
+func formatMessage(from string, message *Message) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(message.Body)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const unmatchedRoute = "unmatched"
+
+type HTTPMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func NewHTTPMetrics(registerer prometheus.Registerer) *HTTPMetrics {
+	labels := []string{"method", "route", "status"}
+
+	m := &HTTPMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of handled http requests.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Latency of handled http requests.",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+	}
+
+	registerer.MustRegister(m.requests, m.duration)
+
+	return m
+}
+
+// Middleware records every request labelled by the route template rather
+// than the raw path, which keeps the label cardinality bounded. It records the
+// status of the response, so handler errors have to be turned into responses
+// further down the chain.
+func (m *HTTPMetrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			started := time.Now()
+
+			err := next(c)
+
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			labels := prometheus.Labels{
+				"method": c.Request().Method,
+				"route":  route,
+				"status": strconv.Itoa(c.Response().Status),
+			}
+
+			m.requests.With(labels).Inc()
+			m.duration.With(labels).Observe(time.Since(started).Seconds())
+
+			return err
+		}
+	}
+}
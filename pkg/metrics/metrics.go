@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewRegistry returns a registry with the go runtime and process collectors
+// and a build info gauge labelled with the application name and version.
+func NewRegistry(name, version string) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+
+	buildInfo := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "app_build_info",
+		Help: "Build information of the running application, always 1.",
+	}, []string{"name", "version", "go_version"})
+	buildInfo.WithLabelValues(name, version, runtime.Version()).Set(1)
+
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		buildInfo,
+	)
+
+	return registry
+}
+
+func Handler(registry *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
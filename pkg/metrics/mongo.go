@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+type MongoMetrics struct {
+	commandDuration *prometheus.HistogramVec
+	commandErrors   *prometheus.CounterVec
+	connections     *prometheus.GaugeVec
+	inUse           *prometheus.GaugeVec
+}
+
+func NewMongoMetrics(registerer prometheus.Registerer) *MongoMetrics {
+	m := &MongoMetrics{
+		commandDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mongo_command_duration_seconds",
+			Help:    "Latency of mongo commands.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"command"}),
+		commandErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mongo_command_errors_total",
+			Help: "Number of failed mongo commands.",
+		}, []string{"command"}),
+		connections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mongo_pool_connections",
+			Help: "Number of open connections in the mongo connection pool.",
+		}, []string{"address"}),
+		inUse: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mongo_pool_connections_in_use",
+			Help: "Number of connections checked out of the mongo connection pool.",
+		}, []string{"address"}),
+	}
+
+	registerer.MustRegister(m.commandDuration, m.commandErrors, m.connections, m.inUse)
+
+	return m
+}
+
+func (m *MongoMetrics) CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			m.commandDuration.WithLabelValues(e.CommandName).Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			m.commandDuration.WithLabelValues(e.CommandName).Observe(e.Duration.Seconds())
+			m.commandErrors.WithLabelValues(e.CommandName).Inc()
+		},
+	}
+}
+
+func (m *MongoMetrics) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.ConnectionCreated:
+				m.connections.WithLabelValues(e.Address).Inc()
+			case event.ConnectionClosed:
+				m.connections.WithLabelValues(e.Address).Dec()
+			case event.GetSucceeded:
+				m.inUse.WithLabelValues(e.Address).Inc()
+			case event.ConnectionReturned:
+				m.inUse.WithLabelValues(e.Address).Dec()
+			}
+		},
+	}
+}
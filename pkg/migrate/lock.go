@@ -0,0 +1,164 @@
+package migrate
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	lockID           = "migrations"
+	lockPollInterval = time.Second
+)
+
+var (
+	ErrLockTimeout = errors.New("timed out waiting for migration lock")
+	ErrLockLost    = errors.New("migration lock was lost")
+)
+
+// lock is a lease stored in Mongo that keeps concurrent replicas from running
+// migrations at the same time. A lease left behind by a crashed owner expires
+// after ttl and can then be taken over, so the owner keeps extending it while
+// it runs.
+type lock struct {
+	collection *mongo.Collection
+	owner      string
+	ttl        time.Duration
+	timeout    time.Duration
+}
+
+func (l *lock) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := l.acquire(ctx); err != nil {
+		return err
+	}
+
+	defer l.release()
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	defer cancel()
+
+	lost := make(chan error, 1)
+
+	go func() {
+		lost <- l.heartbeat(ctx, cancel)
+	}()
+
+	err := fn(ctx)
+
+	cancel()
+
+	// a lost lease means another replica may have started migrating, which
+	// matters more than whatever fn made of its cancelled context
+	if heartbeatErr := <-lost; heartbeatErr != nil {
+		return heartbeatErr
+	}
+
+	return err
+}
+
+// heartbeat extends the lease every third of its ttl until ctx is done. When
+// the lease cannot be extended it cancels the work guarded by the lock.
+func (l *lock) heartbeat(ctx context.Context, cancel context.CancelFunc) error {
+	ticker := time.NewTicker(l.ttl / 3)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := l.renew(ctx); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+
+				cancel()
+				return err
+			}
+		}
+	}
+}
+
+func (l *lock) renew(ctx context.Context) error {
+	filter := bson.M{"_id": lockID, "owner": l.owner}
+	update := bson.M{
+		"$set": bson.M{"expires_at": time.Now().Add(l.ttl)},
+	}
+
+	result, err := l.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return errors.Wrap(err, "renewing migration lock")
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrLockLost
+	}
+
+	return nil
+}
+
+func (l *lock) acquire(ctx context.Context) error {
+	deadline := time.Now().Add(l.timeout)
+
+	for {
+		acquired, err := l.tryAcquire(ctx)
+		if err != nil {
+			return err
+		}
+
+		if acquired {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return ErrLockTimeout
+		}
+
+		select {
+		case <-time.After(lockPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *lock) tryAcquire(ctx context.Context) (bool, error) {
+	now := time.Now()
+
+	filter := bson.M{
+		"_id": lockID,
+		"$or": bson.A{
+			bson.M{"owner": l.owner},
+			bson.M{"expires_at": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{"owner": l.owner, "expires_at": now.Add(l.ttl)},
+	}
+
+	// while the lease is held by someone else the filter does not match and
+	// the upsert collides with the existing _id
+	_, err := l.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "acquiring migration lock")
+	}
+
+	return true, nil
+}
+
+func (l *lock) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	defer cancel()
+
+	_, _ = l.collection.DeleteOne(ctx, bson.M{"_id": lockID, "owner": l.owner})
+}
@@ -0,0 +1,234 @@
+package migrate
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Func func(ctx context.Context, db *mongo.Database) error
+
+// Migration is a single versioned schema change. Versions must be unique and
+// are applied in ascending order; Down reverts what Up did.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      Func
+	Down    Func
+}
+
+type Status struct {
+	Version   uint64
+	Name      string
+	AppliedAt *time.Time
+}
+
+type record struct {
+	Version   uint64    `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+type MigratorDeps struct {
+	Database       *mongo.Database
+	Collection     string
+	LockCollection string
+	LockTTL        time.Duration
+	LockTimeout    time.Duration
+	Owner          string
+}
+
+type Migrator struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+	lock       *lock
+	migrations []Migration
+}
+
+func NewMigrator(deps *MigratorDeps, migrations []Migration) (*Migrator, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	for i := range sorted {
+		if sorted[i].Up == nil || sorted[i].Down == nil {
+			return nil, errors.Errorf("migration %d %s must define up and down steps", sorted[i].Version, sorted[i].Name)
+		}
+
+		if i > 0 && sorted[i].Version == sorted[i-1].Version {
+			return nil, errors.Errorf("duplicate migration version %d", sorted[i].Version)
+		}
+	}
+
+	return &Migrator{
+		db:         deps.Database,
+		collection: deps.Database.Collection(deps.Collection),
+		lock: &lock{
+			collection: deps.Database.Collection(deps.LockCollection),
+			owner:      deps.Owner,
+			ttl:        deps.LockTTL,
+			timeout:    deps.LockTimeout,
+		},
+		migrations: sorted,
+	}, nil
+}
+
+// Up applies every pending migration in version order and returns the ones
+// it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.lock.run(ctx, func(ctx context.Context) error {
+		records, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := records[migration.Version]; ok {
+				continue
+			}
+
+			if err = migration.Up(ctx, m.db); err != nil {
+				return errors.Wrapf(err, "applying migration %d %s", migration.Version, migration.Name)
+			}
+
+			if err = m.record(ctx, &migration); err != nil {
+				return err
+			}
+
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts up to steps most recently applied migrations and returns the
+// ones it reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := m.lock.run(ctx, func(ctx context.Context) error {
+		records, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+
+			if _, ok := records[migration.Version]; !ok {
+				continue
+			}
+
+			if err = migration.Down(ctx, m.db); err != nil {
+				return errors.Wrapf(err, "reverting migration %d %s", migration.Version, migration.Name)
+			}
+
+			if err = m.forget(ctx, &migration); err != nil {
+				return err
+			}
+
+			reverted = append(reverted, migration)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	records, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+
+	for _, migration := range m.migrations {
+		status := Status{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+
+		if applied, ok := records[migration.Version]; ok {
+			appliedAt := applied.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Pending returns the number of registered migrations not applied yet.
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	records, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, migration := range m.migrations {
+		if _, ok := records[migration.Version]; !ok {
+			pending++
+		}
+	}
+
+	return pending, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[uint64]record, error) {
+	var records []record
+
+	cursor, err := m.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading applied migrations")
+	}
+
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, errors.Wrap(err, "decoding applied migrations")
+	}
+
+	result := make(map[uint64]record, len(records))
+	for _, r := range records {
+		result[r.Version] = r
+	}
+
+	return result, nil
+}
+
+func (m *Migrator) record(ctx context.Context, migration *Migration) error {
+	r := record{
+		Version:   migration.Version,
+		Name:      migration.Name,
+		AppliedAt: time.Now(),
+	}
+
+	if _, err := m.collection.InsertOne(ctx, r); err != nil {
+		return errors.Wrapf(err, "recording migration %d %s", migration.Version, migration.Name)
+	}
+
+	return nil
+}
+
+func (m *Migrator) forget(ctx context.Context, migration *Migration) error {
+	if _, err := m.collection.DeleteOne(ctx, bson.M{"_id": migration.Version}); err != nil {
+		return errors.Wrapf(err, "forgetting migration %d %s", migration.Version, migration.Name)
+	}
+
+	return nil
+}
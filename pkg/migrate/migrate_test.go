@@ -0,0 +1,215 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDatabaseURIEnv names a Mongo deployment to apply migrations to. Tests
+// that need one are skipped without it.
+const testDatabaseURIEnv = "TEST_DB_URI"
+
+func noop(context.Context, *mongo.Database) error { return nil }
+
+func testMigration(version uint64) Migration {
+	return Migration{Version: version, Name: fmt.Sprintf("migration_%d", version), Up: noop, Down: noop}
+}
+
+// testDatabase returns a database on the test deployment, or, when uri is
+// empty, a handle of an unconnected client that is enough to build a Migrator.
+func testDatabase(t *testing.T, uri string) *mongo.Database {
+	t.Helper()
+
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("creating mongo client: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = client.Disconnect(context.Background())
+	})
+
+	return client.Database(fmt.Sprintf("gochat_migrate_test_%d", time.Now().UnixNano()))
+}
+
+func versions(migrations []Migration) []uint64 {
+	result := make([]uint64, 0, len(migrations))
+	for _, migration := range migrations {
+		result = append(result, migration.Version)
+	}
+
+	return result
+}
+
+func TestNewMigratorOrdering(t *testing.T) {
+	tests := []struct {
+		name       string
+		migrations []Migration
+		want       []uint64
+		wantErr    bool
+	}{
+		{name: "none", migrations: nil, want: []uint64{}},
+		{
+			name:       "already ordered",
+			migrations: []Migration{testMigration(1), testMigration(2), testMigration(3)},
+			want:       []uint64{1, 2, 3},
+		},
+		{
+			name:       "sorted by version",
+			migrations: []Migration{testMigration(3), testMigration(1), testMigration(2)},
+			want:       []uint64{1, 2, 3},
+		},
+		{
+			name:       "gaps are allowed",
+			migrations: []Migration{testMigration(10), testMigration(2)},
+			want:       []uint64{2, 10},
+		},
+		{
+			name:       "duplicate version",
+			migrations: []Migration{testMigration(2), testMigration(1), testMigration(2)},
+			wantErr:    true,
+		},
+		{
+			name:       "missing down step",
+			migrations: []Migration{testMigration(1), {Version: 2, Name: "up_only", Up: noop}},
+			wantErr:    true,
+		},
+		{
+			name:       "missing up step",
+			migrations: []Migration{{Version: 1, Name: "down_only", Down: noop}},
+			wantErr:    true,
+		},
+	}
+
+	db := testDatabase(t, "")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]Migration(nil), tt.migrations...)
+
+			migrator, err := NewMigrator(&MigratorDeps{Database: db, Collection: "migrations", LockCollection: "migration_lock"}, input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := versions(migrator.migrations); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got versions %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(versions(input), versions(tt.migrations)) {
+				t.Fatal("the migrations passed in were reordered")
+			}
+		})
+	}
+}
+
+func TestMigratorUpDownOrder(t *testing.T) {
+	uri := os.Getenv(testDatabaseURIEnv)
+	if uri == "" {
+		t.Skipf("%s is not set", testDatabaseURIEnv)
+	}
+
+	db := testDatabase(t, uri)
+
+	t.Cleanup(func() {
+		_ = db.Drop(context.Background())
+	})
+
+	var calls []string
+
+	migration := func(version uint64) Migration {
+		return Migration{
+			Version: version,
+			Name:    fmt.Sprintf("migration_%d", version),
+			Up: func(context.Context, *mongo.Database) error {
+				calls = append(calls, fmt.Sprintf("up %d", version))
+				return nil
+			},
+			Down: func(context.Context, *mongo.Database) error {
+				calls = append(calls, fmt.Sprintf("down %d", version))
+				return nil
+			},
+		}
+	}
+
+	newMigrator := func(migrations ...Migration) *Migrator {
+		migrator, err := NewMigrator(&MigratorDeps{
+			Database:       db,
+			Collection:     "migrations",
+			LockCollection: "migration_lock",
+			LockTTL:        time.Minute,
+			LockTimeout:    time.Second,
+			Owner:          "test",
+		}, migrations)
+		if err != nil {
+			t.Fatalf("creating migrator: %v", err)
+		}
+
+		return migrator
+	}
+
+	ctx := context.Background()
+
+	steps := []struct {
+		name string
+		run  func() ([]Migration, error)
+		want []string
+	}{
+		{
+			name: "up applies pending migrations in version order",
+			run:  func() ([]Migration, error) { return newMigrator(migration(2), migration(1)).Up(ctx) },
+			want: []string{"up 1", "up 2"},
+		},
+		{
+			name: "up skips applied migrations",
+			run: func() ([]Migration, error) {
+				return newMigrator(migration(3), migration(1), migration(2)).Up(ctx)
+			},
+			want: []string{"up 3"},
+		},
+		{
+			name: "down reverts the newest migrations first",
+			run: func() ([]Migration, error) {
+				return newMigrator(migration(1), migration(2), migration(3)).Down(ctx, 2)
+			},
+			want: []string{"down 3", "down 2"},
+		},
+		{
+			name: "up reapplies reverted migrations in version order",
+			run: func() ([]Migration, error) {
+				return newMigrator(migration(3), migration(2), migration(1)).Up(ctx)
+			},
+			want: []string{"up 2", "up 3"},
+		},
+	}
+
+	for _, step := range steps {
+		calls = nil
+
+		if _, err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+
+		if !reflect.DeepEqual(calls, step.want) {
+			t.Fatalf("%s: got %v, want %v", step.name, calls, step.want)
+		}
+	}
+}
@@ -39,6 +39,36 @@ func DefaultSignalWaiter(ctx context.Context) error {
 	return WaitSignal(ctx, DefaultOSSignals())
 }
 
+func DefaultReloadSignals() []os.Signal {
+	return []os.Signal{syscall.SIGHUP}
+}
+
+// HandleReload calls reload on every reload signal until ctx is done. A failed
+// reload is logged and leaves the running configuration in place.
+func HandleReload(ctx context.Context, signals Signals, reload func(ctx context.Context) error) error {
+	logger := zerolog.Ctx(ctx)
+
+	reloadSignal := make(chan os.Signal, 1)
+	signal.Notify(reloadSignal, signals...)
+
+	defer signal.Stop(reloadSignal)
+
+	logger.Info().Stringer("signals", signals).Msg("wait reload signals")
+
+	for {
+		select {
+		case s := <-reloadSignal:
+			logger.Info().Msgf("got reload signal: %s", s.String())
+
+			if err := reload(ctx); err != nil {
+				logger.Error().Err(err).Msg("reload configuration")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
 func WaitSignal(ctx context.Context, signals Signals) error {
 	logger := zerolog.Ctx(ctx)
 
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many takes happen between removals of idle buckets.
+const sweepEvery = 1024
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	expires time.Time
+}
+
+// MemoryStore keeps buckets in process memory. Limits are enforced per
+// replica only.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, policy Policy, now time.Time) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.takes++
+	if s.takes%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Limit), updated: now}
+		s.buckets[key] = b
+	}
+
+	elapsed := math.Max(now.Sub(b.updated).Seconds(), 0)
+	tokens := math.Min(float64(policy.Limit), b.tokens+elapsed*policy.rate())
+
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+
+	b.tokens = tokens
+	b.updated = now
+	b.expires = now.Add(policy.Period)
+
+	return newResult(policy, tokens, allowed), nil
+}
+
+// sweep drops buckets idle for a whole period, which are full again and
+// therefore equivalent to missing ones.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.After(b.expires) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoBucket struct {
+	Key     string  `bson:"_id"`
+	Tokens  float64 `bson:"tokens"`
+	Allowed bool    `bson:"allowed"`
+}
+
+// MongoStore keeps buckets in a collection shared by every replica, so that
+// limits hold across the whole deployment. Each take is a single pipeline
+// update, which keeps the refill and the take atomic. Idle buckets are removed
+// by a ttl index on expires_at, which the service creates with its migrations.
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoStore(db *mongo.Database, collection string) *MongoStore {
+	return &MongoStore{
+		collection: db.Collection(collection),
+	}
+}
+
+func (s *MongoStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer cancel()
+
+	limit := float64(policy.Limit)
+	ratePerMillisecond := limit / float64(policy.Period.Milliseconds())
+
+	elapsed := bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updated_at", now}}}}}}
+	refilled := bson.M{"$min": bson.A{limit, bson.M{"$add": bson.A{
+		bson.M{"$ifNull": bson.A{"$tokens", limit}},
+		bson.M{"$multiply": bson.A{elapsed, ratePerMillisecond}},
+	}}}}
+	hasToken := bson.M{"$gte": bson.A{"$tokens", 1}}
+
+	update := bson.A{
+		bson.M{"$set": bson.M{"tokens": refilled}},
+		bson.M{"$set": bson.M{
+			"allowed":    hasToken,
+			"tokens":     bson.M{"$cond": bson.A{hasToken, bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"updated_at": now,
+			"expires_at": now.Add(policy.Period),
+		}},
+	}
+
+	filter := bson.M{"_id": key}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var b mongoBucket
+
+	err := s.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&b)
+	if mongo.IsDuplicateKeyError(err) {
+		// two replicas raced to create the bucket, the retry updates it
+		err = s.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&b)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "taking rate limit token")
+	}
+
+	return newResult(policy, b.Tokens, b.Allowed), nil
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDatabaseURIEnv names a Mongo deployment to run the pipeline of the
+// Mongo store against. The test is skipped without it.
+const testDatabaseURIEnv = "TEST_DB_URI"
+
+func TestMongoStoreTake(t *testing.T) {
+	uri := os.Getenv(testDatabaseURIEnv)
+	if uri == "" {
+		t.Skipf("%s is not set", testDatabaseURIEnv)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connecting to mongo: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = client.Disconnect(context.Background())
+	})
+
+	db := client.Database("gochat_test")
+	collection := fmt.Sprintf("rate_limits_%d", time.Now().UnixNano())
+
+	t.Cleanup(func() {
+		_ = db.Collection(collection).Drop(context.Background())
+	})
+
+	testStore(t, NewMongoStore(db, collection))
+}
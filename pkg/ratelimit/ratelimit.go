@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	DriverMemory = "memory"
+	DriverMongo  = "mongo"
+)
+
+// Policy is a token bucket holding at most Limit tokens that refills at Limit
+// tokens per Period. Every request takes one token.
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+func (p Policy) Validate() error {
+	if p.Limit < 1 || p.Period <= 0 {
+		return errors.Errorf("rate limit policy %q needs a positive limit and period", p.Name)
+	}
+
+	return nil
+}
+
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed. It is zero
+	// for allowed requests.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. Take must refill and take a token atomically, so
+// that concurrent requests sharing a key can not overspend the bucket.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (*Result, error)
+}
+
+var ErrUnknownDriver = errors.New("unknown rate limit store driver")
+
+type Deps struct {
+	Driver     string
+	Database   *mongo.Database
+	Collection string
+}
+
+func NewStore(deps *Deps) (Store, error) {
+	switch deps.Driver {
+	case DriverMemory:
+		return NewMemoryStore(), nil
+	case DriverMongo:
+		return NewMongoStore(deps.Database, deps.Collection), nil
+	default:
+		return nil, errors.Wrap(ErrUnknownDriver, deps.Driver)
+	}
+}
+
+// newResult describes a bucket holding the given tokens after the request was
+// allowed or denied.
+func newResult(policy Policy, tokens float64, allowed bool) *Result {
+	rate := policy.rate()
+
+	result := &Result{
+		Allowed:   allowed,
+		Limit:     policy.Limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(policy.Limit) - tokens) / rate),
+	}
+
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+
+	return time.Duration(s * float64(time.Second))
+}
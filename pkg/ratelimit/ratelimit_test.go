@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type take struct {
+	after      time.Duration
+	allowed    bool
+	remaining  int
+	retryAfter time.Duration
+}
+
+// storeCases drive a bucket of 2 tokens refilling at 2 tokens per second.
+// Every take happens after the given offset from the start of the case.
+var storeCases = []struct {
+	name  string
+	takes []take
+}{
+	{
+		name: "fresh bucket allows up to the limit",
+		takes: []take{
+			{after: 0, allowed: true, remaining: 1},
+			{after: 0, allowed: true, remaining: 0},
+			{after: 0, allowed: false, remaining: 0, retryAfter: 500 * time.Millisecond},
+		},
+	},
+	{
+		name: "bucket refills with elapsed time",
+		takes: []take{
+			{after: 0, allowed: true, remaining: 1},
+			{after: 0, allowed: true, remaining: 0},
+			{after: 500 * time.Millisecond, allowed: true, remaining: 0},
+		},
+	},
+	{
+		name: "partial refill is not enough for a request",
+		takes: []take{
+			{after: 0, allowed: true, remaining: 1},
+			{after: 0, allowed: true, remaining: 0},
+			{after: 250 * time.Millisecond, allowed: false, remaining: 0, retryAfter: 250 * time.Millisecond},
+		},
+	},
+	{
+		name: "refill is capped at the limit",
+		takes: []take{
+			{after: 0, allowed: true, remaining: 1},
+			{after: time.Minute, allowed: true, remaining: 1},
+		},
+	},
+	{
+		name: "clock going backwards does not refill",
+		takes: []take{
+			{after: time.Second, allowed: true, remaining: 1},
+			{after: time.Second, allowed: true, remaining: 0},
+			{after: 0, allowed: false, remaining: 0, retryAfter: 500 * time.Millisecond},
+		},
+	},
+}
+
+func testStore(t *testing.T, store Store) {
+	policy := Policy{Name: "test", Limit: 2, Period: time.Second}
+	start := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range storeCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for n, want := range tc.takes {
+				result, err := store.Take(context.Background(), tc.name, policy, start.Add(want.after))
+				if err != nil {
+					t.Fatalf("take %d: %v", n, err)
+				}
+
+				if result.Allowed != want.allowed || result.Remaining != want.remaining || result.RetryAfter != want.retryAfter {
+					t.Fatalf("take %d: got allowed %v, remaining %d, retry after %s; want %v, %d, %s",
+						n, result.Allowed, result.Remaining, result.RetryAfter, want.allowed, want.remaining, want.retryAfter)
+				}
+
+				if result.Limit != policy.Limit {
+					t.Fatalf("take %d: got limit %d, want %d", n, result.Limit, policy.Limit)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{name: "valid", policy: Policy{Limit: 1, Period: time.Second}},
+		{name: "zero limit", policy: Policy{Limit: 0, Period: time.Second}, wantErr: true},
+		{name: "zero period", policy: Policy{Limit: 1}, wantErr: true},
+		{name: "negative period", policy: Policy{Limit: 1, Period: -time.Second}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package token
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type JWTManagerDeps struct {
+	SigningKey string
+	Issuer     string
+	TTL        time.Duration
+}
+
+type JWTManager struct {
+	signingKey []byte
+	issuer     string
+	ttl        time.Duration
+}
+
+type Claims struct {
+	Role string `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func NewJWTManager(deps *JWTManagerDeps) (*JWTManager, error) {
+	if deps.SigningKey == "" {
+		return nil, errors.New("jwt signing key is empty")
+	}
+
+	if deps.TTL <= 0 {
+		return nil, errors.New("jwt ttl must be positive")
+	}
+
+	return &JWTManager{
+		signingKey: []byte(deps.SigningKey),
+		issuer:     deps.Issuer,
+		ttl:        deps.TTL,
+	}, nil
+}
+
+func (m *JWTManager) TTL() time.Duration {
+	return m.ttl
+}
+
+func (m *JWTManager) Generate(subject, role string) (string, error) {
+	now := time.Now()
+
+	claims := &Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    m.issuer,
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.signingKey)
+	if err != nil {
+		return "", errors.Wrap(err, "signing jwt")
+	}
+
+	return signed, nil
+}
+
+func (m *JWTManager) Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		claims,
+		func(_ *jwt.Token) (interface{}, error) {
+			return m.signingKey, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing jwt")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	httpTracerName = "github.com/Meystergod/gochat/pkg/tracing/http"
+	unmatchedRoute = "unmatched"
+)
+
+type HTTPTracingDeps struct {
+	TracerProvider trace.TracerProvider
+	Propagator     propagation.TextMapPropagator
+	// Logger is stored in the request context annotated with the ids of the
+	// server span.
+	Logger *zerolog.Logger
+}
+
+type HTTPTracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	logger     *zerolog.Logger
+}
+
+func NewHTTPTracing(deps *HTTPTracingDeps) *HTTPTracing {
+	return &HTTPTracing{
+		tracer:     deps.TracerProvider.Tracer(httpTracerName),
+		propagator: deps.Propagator,
+		logger:     deps.Logger,
+	}
+}
+
+// Middleware starts a server span for every request, continuing the trace of
+// the incoming traceparent header when there is one. The span carries the
+// status of the response, so handler errors have to be turned into responses
+// further down the chain.
+func (t *HTTPTracing) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			request := c.Request()
+
+			ctx := t.propagator.Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			ctx, span := t.tracer.Start(ctx, fmt.Sprintf("%s %s", request.Method, route),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethod(request.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(request.URL.Path),
+					semconv.ClientAddress(c.RealIP()),
+					semconv.UserAgentOriginal(request.UserAgent()),
+				),
+			)
+			defer span.End()
+
+			c.SetRequest(request.WithContext(ContextWithLogger(ctx, t.logger)))
+
+			err := next(c)
+
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPStatusCode(status))
+
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ContextWithLogger stores the logger in ctx, annotated with the trace and span
+// ids of the span in ctx when there is one, so that zerolog.Ctx returns a
+// logger whose records can be correlated with the trace.
+func ContextWithLogger(ctx context.Context, logger *zerolog.Logger) context.Context {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return logger.WithContext(ctx)
+	}
+
+	annotated := logger.With().
+		Str("trace_id", spanContext.TraceID().String()).
+		Str("span_id", spanContext.SpanID().String()).
+		Logger()
+
+	return annotated.WithContext(ctx)
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const mongoTracerName = "github.com/Meystergod/gochat/pkg/tracing/mongo"
+
+type mongoSpanKey struct {
+	connectionID string
+	requestID    int64
+}
+
+// MongoTracing creates a client span for every command sent by the mongo
+// driver, as a child of the span in the context of the operation.
+type MongoTracing struct {
+	tracer trace.Tracer
+	spans  sync.Map
+}
+
+func NewMongoTracing(provider trace.TracerProvider) *MongoTracing {
+	return &MongoTracing{
+		tracer: provider.Tracer(mongoTracerName),
+	}
+}
+
+func (t *MongoTracing) CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			attributes := []attribute.KeyValue{
+				semconv.DBSystemMongoDB,
+				semconv.DBName(e.DatabaseName),
+				semconv.DBOperation(e.CommandName),
+			}
+
+			name := e.CommandName
+
+			// the command document names the target collection in the
+			// value of its first element
+			if collection, ok := e.Command.Lookup(e.CommandName).StringValueOK(); ok {
+				attributes = append(attributes, semconv.DBMongoDBCollection(collection))
+				name = fmt.Sprintf("%s %s", e.CommandName, collection)
+			}
+
+			_, span := t.tracer.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attributes...),
+			)
+
+			t.spans.Store(mongoSpanKey{connectionID: e.ConnectionID, requestID: e.RequestID}, span)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			if span, ok := t.finish(&e.CommandFinishedEvent); ok {
+				span.End()
+			}
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			if span, ok := t.finish(&e.CommandFinishedEvent); ok {
+				span.SetStatus(codes.Error, e.Failure)
+				span.End()
+			}
+		},
+	}
+}
+
+func (t *MongoTracing) finish(e *event.CommandFinishedEvent) (trace.Span, bool) {
+	value, ok := t.spans.LoadAndDelete(mongoSpanKey{connectionID: e.ConnectionID, requestID: e.RequestID})
+	if !ok {
+		return nil, false
+	}
+
+	return value.(trace.Span), true
+}
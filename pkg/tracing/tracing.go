@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterNone   = "none"
+)
+
+var ErrUnknownExporter = errors.New("unknown tracing exporter")
+
+type Deps struct {
+	Exporter       string
+	ServiceName    string
+	ServiceVersion string
+
+	// OTLPEndpoint is the host:port of an OTLP/HTTP collector.
+	OTLPEndpoint string
+	OTLPInsecure bool
+
+	// SampleRatio is the fraction of new traces that are sampled. Requests
+	// that carry a sampled parent are always traced.
+	SampleRatio float64
+}
+
+// NewTracerProvider creates a provider exporting spans with the configured
+// exporter. The none exporter still generates trace and span ids, so that
+// logs can be correlated, but nothing leaves the process. The provider must
+// be shut down to flush the buffered spans.
+func NewTracerProvider(ctx context.Context, deps *Deps) (*sdktrace.TracerProvider, error) {
+	if deps.SampleRatio < 0 || deps.SampleRatio > 1 {
+		return nil, errors.New("tracing sample ratio must be between 0 and 1")
+	}
+
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(deps.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(deps.ServiceName),
+			semconv.ServiceVersion(deps.ServiceVersion),
+		)),
+	}
+
+	switch deps.Exporter {
+	case ExporterOTLP:
+		clientOptions := []otlptracehttp.Option{otlptracehttp.WithEndpoint(deps.OTLPEndpoint)}
+		if deps.OTLPInsecure {
+			clientOptions = append(clientOptions, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err := otlptracehttp.New(ctx, clientOptions...)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating otlp exporter")
+		}
+
+		options = append(options, sdktrace.WithBatcher(exporter))
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, errors.Wrap(err, "creating stdout exporter")
+		}
+
+		options = append(options, sdktrace.WithSyncer(exporter))
+	case ExporterNone:
+	default:
+		return nil, errors.Wrap(ErrUnknownExporter, deps.Exporter)
+	}
+
+	return sdktrace.NewTracerProvider(options...), nil
+}
+
+// Error marks the span as failed with err and returns err, so that it can be
+// used on the return statement.
+func Error(span trace.Span, err error) error {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+
+	return err
+}